@@ -38,7 +38,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"user": user})
+	// the new user sees their own email, the model itself only marshals the public projection
+	c.JSON(http.StatusCreated, gin.H{"user": user.Self()})
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
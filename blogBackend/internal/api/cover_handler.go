@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"project/internal/service"
+	"project/internal/store"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CoverHandler struct {
+	coverService service.CoverService
+}
+
+func NewCoverHandler(svc service.CoverService) *CoverHandler {
+	return &CoverHandler{coverService: svc}
+}
+
+func (h *CoverHandler) UploadCover(c *gin.Context) {
+	idStr := c.Param("id")
+	bookID, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	// leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxCoverSize+1<<20)
+	fileHeader, err := c.FormFile("cover")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cover image is required in the 'cover' field"})
+		return
+	}
+	if fileHeader.Size > service.MaxCoverSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "cover image is too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	book, urls, err := h.coverService.UploadCover(int(bookID), userIDInt, file)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": book, "covers": urls})
+}
+
+// GetCover serves cover images. Cover IDs are content hashes, so responses never change and are cached for a year.
+func (h *CoverHandler) GetCover(c *gin.Context) {
+	coverID, size := c.Param("coverId"), c.Param("size")
+	etag := `"` + coverID + "-" + size + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := h.coverService.OpenCover(coverID, size)
+	if errors.Is(err, store.ErrBlobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cover not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	c.Header("Content-Type", info.ContentType)
+	if info.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, body); err != nil {
+		log.Printf("GetCover - failed to send cover %s/%s: %v", coverID, size, err)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"project/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DuplicateHandler struct {
+	duplicateService service.DuplicateService
+}
+
+func NewDuplicateHandler(svc service.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{duplicateService: svc}
+}
+
+type MergeBooksInput struct {
+	DuplicateID int `json:"duplicateId" binding:"required"`
+}
+
+func (h *DuplicateHandler) GetDuplicates(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	groups, err := h.duplicateService.FindDuplicates(userIDInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"duplicates": groups})
+}
+
+// MergeBooks merges the book log in the body into the one in the path, the one in the path survives.
+func (h *DuplicateHandler) MergeBooks(c *gin.Context) {
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var input MergeBooksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, err := h.duplicateService.MergeBooks(userIDInt, bookID, input.DuplicateID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": book})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EPUBHandler struct {
+	epubService service.EPUBService
+}
+
+func NewEPUBHandler(svc service.EPUBService) *EPUBHandler {
+	return &EPUBHandler{epubService: svc}
+}
+
+// ImportEPUB creates a book log from the uploaded 'epub' file. The optional 'status' form field
+// defaults to Want to Read, ?preview=true only returns the pre-filled book log without saving it or its cover.
+func (h *EPUBHandler) ImportEPUB(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	// leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxEPUBSize+1<<20)
+	fileHeader, err := c.FormFile("epub")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "EPUB file is required in the 'epub' field"})
+		return
+	}
+	if fileHeader.Size > service.MaxEPUBSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "EPUB file is too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	preview := c.Query("preview") == "true"
+	result, err := h.epubService.ImportEPUB(userIDInt, file, fileHeader.Size, c.PostForm("status"), preview)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusCreated
+	if preview {
+		status = http.StatusOK
+	}
+	c.JSON(status, result)
+}
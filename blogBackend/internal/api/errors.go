@@ -0,0 +1,21 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"project/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// errorStatus picks the HTTP status for an error returned by a service
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
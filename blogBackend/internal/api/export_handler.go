@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"project/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService service.ExportService
+}
+
+func NewExportHandler(svc service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: svc}
+}
+
+func (h *ExportHandler) ExportBooks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	format := c.DefaultQuery("format", service.ExportFormatJSON)
+	var contentType, extension string
+	switch format {
+	case service.ExportFormatCSV:
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	case service.ExportFormatJSON:
+		contentType, extension = "application/json; charset=utf-8", "json"
+	case service.ExportFormatMarkdown:
+		// one note per book, bundled as a zip archive
+		contentType, extension = "application/zip", "zip"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrUnsupportedExportFormat.Error()})
+		return
+	}
+
+	fileName := "library-" + time.Now().Format("2006-01-02") + "." + extension
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Status(http.StatusOK)
+
+	// headers are already sent once streaming starts, so errors can only be logged
+	if err := h.exportService.ExportBooks(userIDInt, format, c.Writer); err != nil {
+		log.Printf("ExportBooks - export for user %d failed: %v", userIDInt, err)
+	}
+}
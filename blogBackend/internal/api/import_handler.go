@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"project/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits the size of an uploaded CSV export
+const maxImportFileSize = 10 << 20
+
+type ImportHandler struct {
+	importService service.ImportService
+}
+
+func NewImportHandler(svc service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: svc}
+}
+
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required in the 'file' field"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file is too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// source is optional, the format is detected from the header otherwise
+	job, err := h.importService.StartImport(userIDInt, c.PostForm("source"), file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	idStr := c.Param("id")
+	jobID, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	job, err := h.importService.GetImportJob(int(jobID), userIDInt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LeaderboardHandler struct {
+	leaderboardService service.LeaderboardService
+}
+
+func NewLeaderboardHandler(leaderboardService service.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{leaderboardService: leaderboardService}
+}
+
+// GetLeaderboard ranks the user and the readers they follow, ?period=week|month and ?metric=minutes|books.
+func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	leaderboard, err := h.leaderboardService.GetLeaderboard(userIDInt, c.Query("period"), c.Query("metric"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"leaderboard": leaderboard})
+}
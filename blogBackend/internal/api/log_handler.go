@@ -1,11 +1,13 @@
 package api
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"project/internal/model"
 	"project/internal/service"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,6 +48,11 @@ type CreateBookLogInput struct {
 	Status      string `json:"status" binding:"required"`
 	MyRating    *int   `json:"myRating"`
 	MyComment   string `json:"myComment"`
+	PageCount   int    `json:"pageCount"`
+	// FinishedAt defaults to now when the status is "Read"
+	FinishedAt *time.Time `json:"finishedAt"`
+	// Visibility defaults to the user's default visibility
+	Visibility string `json:"visibility"`
 }
 
 func (h *LogHandler) CreateBookLog(c *gin.Context) {
@@ -87,9 +94,12 @@ func (h *LogHandler) CreateBookLog(c *gin.Context) {
 		Status:      input.Status,
 		MyRating:    input.MyRating,
 		MyComment:   input.MyComment,
+		PageCount:   input.PageCount,
+		FinishedAt:  input.FinishedAt,
+		Visibility:  input.Visibility,
 	}
 	if err := h.logService.CreateBookLog(userIDInt, bookLog); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Book log created successfully"})
@@ -119,11 +129,26 @@ func (h *LogHandler) GetBookLog(c *gin.Context) {
 
 	status := c.Query("status")
 
-	books, err := h.logService.FindBookLogByStatus(userIDInt, status)
+	var books []model.BookLog
+	var err error
+	if tag := c.Query("tag"); tag != "" {
+		books, err = h.logService.FindBookLogsByTag(userIDInt, tag)
+	} else {
+		books, err = h.logService.FindBookLogByStatus(userIDInt, status)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if c.Query("group") == "series" {
+		groups, err := h.logService.GroupBySeries(userIDInt, books)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"groups": groups})
+		return
+	}
 	if len(books) == 0 {
 		c.JSON(http.StatusOK, gin.H{"books": []interface{}{}})
 		return
@@ -162,7 +187,12 @@ func (h *LogHandler) GetBook(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"book": book})
+	next, err := h.logService.GetNextInSeries(book)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": book, "next_in_series": next})
 }
 
 func (h *LogHandler) UpdateBookLog(c *gin.Context) {
@@ -204,13 +234,53 @@ func (h *LogHandler) UpdateBookLog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Book log updated successfully", "book": updatelog})
 }
 
+// PatchBookLog accepts a JSON Merge Patch, fields left out of the body are not touched.
+func (h *LogHandler) PatchBookLog(c *gin.Context) {
+	idStr := c.Param("id")
+	BookID, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	userIDRaw, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userIDRaw.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON object"})
+		return
+	}
+
+	book, err := h.logService.PatchLog(int(BookID), userIDInt, patch)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": book})
+}
+
 func (h *LogHandler) SearchBook(c *gin.Context) {
 	q := c.Query("query")
 	if q == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
 		return
 	}
-	books, err := h.logService.SearchBookByTitleOrAuthor(q)
+	books, err := h.logService.SearchBookByTitleOrAuthor(q, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -221,3 +291,144 @@ func (h *LogHandler) SearchBook(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"books": books})
 }
+
+type BatchBookLogInput struct {
+	Operations []service.BatchOperationInput `json:"operations" binding:"required,dive"`
+}
+
+// BatchUpdateBookLogs applies status, rating, tag and delete operations to many book logs at once.
+// It is all or nothing: when any item is rejected the response lists why and nothing is changed.
+func (h *LogHandler) BatchUpdateBookLogs(c *gin.Context) {
+	userIDRaw, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userIDRaw.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var input BatchBookLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, applied, err := h.logService.BatchUpdate(userIDInt, input.Operations)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !applied {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"applied": false, "results": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"applied": true, "results": results})
+}
+
+func (h *LogHandler) GetTags(c *gin.Context) {
+	userIDRaw, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userIDRaw.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	tags, err := h.logService.GetTags(userIDInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// GetBookHistory lists the revisions of a book log, newest first.
+func (h *LogHandler) GetBookHistory(c *gin.Context) {
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	revisions, err := h.logService.GetHistory(bookID, userIDInt)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// RevertBookRevision restores the book to a past revision, the revert itself is added to the history.
+func (h *LogHandler) RevertBookRevision(c *gin.Context) {
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	revisionID, err := strconv.Atoi(c.Param("revisionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	book, err := h.logService.RevertRevision(bookID, userIDInt, revisionID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": book})
+}
@@ -1,11 +1,12 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
-	"os"
-	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -18,50 +19,110 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		// Split the header to get the token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		if msg := authenticate(c, authHeader); msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
 			c.Abort()
 			return
 		}
+	}
+}
 
-		tokenString := parts[1]
-
-		//Parse the JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			jwtSecret := os.Getenv("JWT_SECRET")
-			if jwtSecret == "" {
-				return nil, fmt.Errorf("JWT_SECRET is not set in the environment variables")
-			}
-			return []byte(jwtSecret), nil
-		})
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: " + err.Error()})
+// OptionalAuthMiddleware identifies the caller when a token is sent, anonymous requests pass through
+// without "userID". A token that is sent but invalid is still rejected.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			return
+		}
+		if msg := authenticate(c, authHeader); msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
 			c.Abort()
 			return
 		}
+	}
+}
+
+// authenticate validates a bearer token and sets the user in the context, it returns an error message on failure
+func authenticate(c *gin.Context, authHeader string) string {
+	// Split the header to get the token
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "Invalid authorization format"
+	}
+
+	tokenString := parts[1]
+
+	//Parse the JWT token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		jwtSecret := os.Getenv("JWT_SECRET")
+		if jwtSecret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is not set in the environment variables")
+		}
+		return []byte(jwtSecret), nil
+	})
+	if err != nil {
+		return "Invalid token: " + err.Error()
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "Invalid token claims"
+	}
+	// Check if the token is expired
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().Unix() > int64(exp) {
+			return "Token has expired"
+		}
+	}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Check if the token is expired
-			if exp, ok := claims["exp"].(float64); ok {
-				if time.Now().Unix() > int64(exp) {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
-					c.Abort()
+	// Set user information in context
+	c.Set("userID", claims["user_id"])
+	c.Set("email", claims["email"])
+	return ""
+}
+
+// CredentialValidator checks the credentials of clients that can't send a JWT
+type CredentialValidator interface {
+	AuthenticateToken(value string) (uint, error)
+	AuthenticateBasic(email string, secret string) (uint, error)
+}
+
+// ReaderAuthMiddleware authenticates e-reader apps with HTTP Basic (email plus password or personal token)
+// or a personal token as the bearer token. A JWT is accepted as well so the web app can use the same routes.
+func ReaderAuthMiddleware(validator CredentialValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID uint
+		var err error
+		authHeader := c.GetHeader("Authorization")
+		switch {
+		case strings.HasPrefix(authHeader, "Basic "):
+			email, secret, ok := c.Request.BasicAuth()
+			if !ok {
+				err = fmt.Errorf("invalid basic credentials")
+				break
+			}
+			userID, err = validator.AuthenticateBasic(email, secret)
+		case strings.HasPrefix(authHeader, "Bearer "):
+			token := strings.TrimPrefix(authHeader, "Bearer ")
+			if userID, err = validator.AuthenticateToken(token); err != nil {
+				if msg := authenticate(c, authHeader); msg == "" {
 					return
 				}
 			}
-
-			// Set user information in context
-			c.Set("userID", claims["user_id"])
-			c.Set("email", claims["email"])
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		default:
+			err = fmt.Errorf("authorization is required")
+		}
+		if err != nil {
+			// readers show a login prompt on this challenge
+			c.Header("WWW-Authenticate", `Basic realm="Library", charset="UTF-8"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
-		}	
+		}
+		c.Set("userID", userID)
 	}
-}
\ No newline at end of file
+}
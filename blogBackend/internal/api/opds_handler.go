@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"project/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OPDSHandler struct {
+	opdsService service.OPDSService
+}
+
+func NewOPDSHandler(svc service.OPDSService) *OPDSHandler {
+	return &OPDSHandler{opdsService: svc}
+}
+
+// catalogUserID reads the user set by ReaderAuthMiddleware, it answers the request itself when there is none
+func catalogUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return 0, false
+	}
+	switch v := userID.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case uint:
+		return int(v), true
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return 0, false
+	}
+}
+
+func writeXML(c *gin.Context, contentType string, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType+";charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+func (h *OPDSHandler) writeFeed(c *gin.Context, load func(userID int) (*service.OPDSFeed, error)) {
+	userID, ok := catalogUserID(c)
+	if !ok {
+		return
+	}
+	feed, err := load(userID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	writeXML(c, feed.Kind, feed)
+}
+
+func (h *OPDSHandler) GetRoot(c *gin.Context) {
+	h.writeFeed(c, h.opdsService.GetRoot)
+}
+
+func (h *OPDSHandler) GetStatuses(c *gin.Context) {
+	h.writeFeed(c, h.opdsService.GetStatuses)
+}
+
+func (h *OPDSHandler) GetShelves(c *gin.Context) {
+	h.writeFeed(c, h.opdsService.GetShelves)
+}
+
+func (h *OPDSHandler) GetAuthors(c *gin.Context) {
+	h.writeFeed(c, h.opdsService.GetAuthors)
+}
+
+// GetBooks accepts one of ?status=, ?shelf=, ?author= or ?q= and ?page=.
+func (h *OPDSHandler) GetBooks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	query := service.OPDSBooksQuery{
+		Status: c.Query("status"),
+		Shelf:  c.Query("shelf"),
+		Author: c.Query("author"),
+		Search: c.Query("q"),
+		Page:   page,
+	}
+	h.writeFeed(c, func(userID int) (*service.OPDSFeed, error) {
+		return h.opdsService.GetBooks(userID, query)
+	})
+}
+
+func (h *OPDSHandler) GetOpenSearch(c *gin.Context) {
+	writeXML(c, service.OpenSearchType, h.opdsService.GetOpenSearch())
+}
@@ -2,17 +2,12 @@ package api
 
 import (
 	"net/http"
-	"project/internal/model"
 	"project/internal/service"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-type ReadInput struct {
-	Time int       `json:"data" binding:"required"`
-	Date time.Time `json:"date" binding:"required"`
-}
 type ReadHandler struct {
 	CreateReadService service.ReadService
 }
@@ -22,7 +17,7 @@ func NewReadHandler(svc service.ReadService) *ReadHandler {
 }
 
 func (h *ReadHandler) CreateReadTime(c *gin.Context) {
-	var input ReadInput
+	var input service.ReadSessionInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -46,17 +41,13 @@ func (h *ReadHandler) CreateReadTime(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID type"})
 		return
 	}
-	read := &model.Read{
-		Time: input.Time,
-	}
-
-	err := h.CreateReadService.CreateReadTime(UserIDInt, read)
+	read, err := h.CreateReadService.CreateReadTime(UserIDInt, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Read time created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Read time created successfully", "read": read})
 }
 
 
@@ -87,4 +78,286 @@ func (h *ReadHandler) GetWeeklyReadTime(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"reads": reads})
-}
\ No newline at end of file
+}
+
+// GetReadTimeStats totals the reading of the current ?range=week|month|year (default week)
+// per day, week or month in the ?tz= timezone (default the user's), compared with the period before.
+func (h *ReadHandler) GetReadTimeStats(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	stats, err := h.CreateReadService.GetReadTimeStats(userIDInt, c.Query("range"), c.Query("tz"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetReadingStreaks returns the current and longest reading streak and a per-day heatmap of ?year= (default this year).
+func (h *ReadHandler) GetReadingStreaks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	year := 0
+	if raw := c.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		year = parsed
+	}
+
+	streaks, err := h.CreateReadService.GetReadingStreaks(userIDInt, year)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"streaks": streaks})
+}
+
+// UploadReadTimes stores sessions logged offline, every session reports whether it was created, a duplicate, deleted or invalid.
+func (h *ReadHandler) UploadReadTimes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var input service.ReadBatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.CreateReadService.UploadReadTimes(userIDInt, input)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// SyncReadTimes returns the sessions changed and deleted since ?cursor=, at most ?limit= (default 100, max 500) at a time.
+func (h *ReadHandler) SyncReadTimes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	changes, err := h.CreateReadService.SyncReadTimes(userIDInt, c.Query("cursor"), limit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// GetReadTimes pages through reading sessions, ?bookId= and ?from=&to= (YYYY-MM-DD) filter them.
+func (h *ReadHandler) GetReadTimes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := service.ReadQuery{From: c.Query("from"), To: c.Query("to"), Page: page, PageSize: pageSize}
+	if raw := c.Query("bookId"); raw != "" {
+		bookID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+			return
+		}
+		id := uint(bookID)
+		query.BookID = &id
+	}
+
+	reads, total, err := h.CreateReadService.GetReadTimes(userIDInt, query)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reads": reads, "page": gin.H{"current": page, "size": pageSize, "total": total, "totalPages": (total + int64(pageSize) - 1) / int64(pageSize)}})
+}
+
+func (h *ReadHandler) GetReadTime(c *gin.Context) {
+	readID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid read ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	read, err := h.CreateReadService.GetReadTime(readID, userIDInt)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"read": read})
+}
+
+func (h *ReadHandler) UpdateReadTime(c *gin.Context) {
+	readID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid read ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var input service.ReadSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	read, err := h.CreateReadService.UpdateReadTime(readID, userIDInt, input)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"read": read})
+}
+
+func (h *ReadHandler) DeleteReadTime(c *gin.Context) {
+	readID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid read ID"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	if err := h.CreateReadService.DeleteReadTime(readID, userIDInt); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Read time deleted successfully"})
+}
@@ -9,11 +9,32 @@ import (
 )
 
 type HandlerDependencies struct {
-	AuthService  service.AuthService
-	LogService   service.LogService
-	ForumService service.ForumService
-	ReadService  service.ReadService
-	ChatService  service.ChatService
+	AuthService           service.AuthService
+	LogService            service.LogService
+	ForumService          service.ForumService
+	ReadService           service.ReadService
+	ChatService           service.ChatService
+	ImportService         service.ImportService
+	ExportService         service.ExportService
+	HighlightService      service.HighlightService
+	ReviewService         service.ReviewService
+	GoalService           service.GoalService
+	RecommendationService service.RecommendationService
+	CoverService          service.CoverService
+	DuplicateService      service.DuplicateService
+	SeriesService         service.SeriesService
+	WorkService           service.WorkService
+	UserService           service.UserService
+	StatsService          service.StatsService
+	LoanService           service.LoanService
+	NotificationService   service.NotificationService
+	QueueService          service.QueueService
+	EPUBService           service.EPUBService
+	TokenService          service.TokenService
+	OPDSService           service.OPDSService
+	TimerService          service.TimerService
+	MinutesGoalService    service.MinutesGoalService
+	LeaderboardService    service.LeaderboardService
 }
 
 func NewRouter(deps HandlerDependencies) *gin.Engine {
@@ -25,7 +46,7 @@ func NewRouter(deps HandlerDependencies) *gin.Engine {
 
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept"}
 
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 
 	config.ExposeHeaders = []string{"Content-Length"}
 
@@ -41,6 +62,27 @@ func NewRouter(deps HandlerDependencies) *gin.Engine {
 	forumHandler := NewForumHandler(deps.ForumService)
 	readHandler := NewReadHandler(deps.ReadService)
 	chatHandler := NewChatHandler(deps.ChatService)
+	importHandler := NewImportHandler(deps.ImportService)
+	exportHandler := NewExportHandler(deps.ExportService)
+	highlightHandler := NewHighlightHandler(deps.HighlightService)
+	reviewHandler := NewReviewHandler(deps.ReviewService)
+	goalHandler := NewGoalHandler(deps.GoalService)
+	recommendationHandler := NewRecommendationHandler(deps.RecommendationService)
+	coverHandler := NewCoverHandler(deps.CoverService)
+	duplicateHandler := NewDuplicateHandler(deps.DuplicateService)
+	seriesHandler := NewSeriesHandler(deps.SeriesService)
+	workHandler := NewWorkHandler(deps.WorkService)
+	userHandler := NewUserHandler(deps.UserService)
+	statsHandler := NewStatsHandler(deps.StatsService)
+	loanHandler := NewLoanHandler(deps.LoanService)
+	notificationHandler := NewNotificationHandler(deps.NotificationService)
+	queueHandler := NewQueueHandler(deps.QueueService)
+	epubHandler := NewEPUBHandler(deps.EPUBService)
+	tokenHandler := NewTokenHandler(deps.TokenService)
+	opdsHandler := NewOPDSHandler(deps.OPDSService)
+	timerHandler := NewTimerHandler(deps.TimerService)
+	minutesGoalHandler := NewMinutesGoalHandler(deps.MinutesGoalService)
+	leaderboardHandler := NewLeaderboardHandler(deps.LeaderboardService)
 	apiV1 := router.Group("/api/v1")
 	{
 		authGroup := apiV1.Group("/auth")
@@ -64,11 +106,174 @@ func NewRouter(deps HandlerDependencies) *gin.Engine {
 		booksGroup := apiV1.Group("/books")
 		booksGroup.Use(middleware.AuthMiddleware())
 		{
+			booksGroup.POST("/batch", logHandler.BatchUpdateBookLogs)
+			booksGroup.GET("/tags", logHandler.GetTags)
+			booksGroup.GET("/duplicates", duplicateHandler.GetDuplicates)
+			booksGroup.POST("/epub", epubHandler.ImportEPUB)
 			booksGroup.GET("/:id", logHandler.GetBook)
 			booksGroup.PUT("/:id", logHandler.UpdateBookLog)
+			booksGroup.PATCH("/:id", logHandler.PatchBookLog)
+			booksGroup.GET("/:id/history", logHandler.GetBookHistory)
+			booksGroup.POST("/:id/history/:revisionId/revert", logHandler.RevertBookRevision)
+			booksGroup.GET("/:id/highlights", highlightHandler.GetBookHighlights)
+			booksGroup.POST("/:id/highlights", highlightHandler.CreateHighlight)
+			booksGroup.POST("/:id/review", reviewHandler.PublishReview)
+			booksGroup.DELETE("/:id/review", reviewHandler.UnpublishReview)
+			booksGroup.POST("/:id/cover", coverHandler.UploadCover)
+			booksGroup.POST("/:id/merge", duplicateHandler.MergeBooks)
+			booksGroup.GET("/:id/loans", loanHandler.GetBookLoans)
+			booksGroup.POST("/:id/loans", loanHandler.LendBook)
 		}
 
+		// covers are public so they can be used directly in <img> tags
+		apiV1.GET("/covers/:coverId/:size", coverHandler.GetCover)
+
+		// the feed is public, signed in readers also see followers-only books of users they follow mutually
+		reviewsGroup := apiV1.Group("/reviews")
+		reviewsGroup.Use(middleware.OptionalAuthMiddleware())
+		{
+			reviewsGroup.GET("", reviewHandler.GetReviews)
+			reviewsGroup.GET("/:id", reviewHandler.GetReview)
+			reviewsGroup.POST("/:id/like", middleware.AuthMiddleware(), reviewHandler.LikeReview)
+			reviewsGroup.DELETE("/:id/like", middleware.AuthMiddleware(), reviewHandler.UnlikeReview)
+			reviewsGroup.GET("/:id/comments", reviewHandler.GetComments)
+			reviewsGroup.POST("/:id/comments", middleware.AuthMiddleware(), reviewHandler.CreateComment)
+			reviewsGroup.DELETE("/:id/comments/:commentId", middleware.AuthMiddleware(), reviewHandler.DeleteComment)
+		}
+
+		highlightGroup := apiV1.Group("/highlights")
+		highlightGroup.Use(middleware.AuthMiddleware())
+		{
+			highlightGroup.GET("/search", highlightHandler.SearchHighlights)
+			highlightGroup.GET("/random", highlightHandler.GetRandomHighlight)
+			highlightGroup.GET("/:id", highlightHandler.GetHighlight)
+			highlightGroup.PUT("/:id", highlightHandler.UpdateHighlight)
+			highlightGroup.DELETE("/:id", highlightHandler.DeleteHighlight)
+		}
+
+		importGroup := apiV1.Group("/import")
+		importGroup.Use(middleware.AuthMiddleware())
+		{
+			importGroup.POST("", importHandler.StartImport)
+			importGroup.GET("/:id", importHandler.GetImportJob)
+		}
+
+		exportGroup := apiV1.Group("/export")
+		exportGroup.Use(middleware.AuthMiddleware())
+		{
+			exportGroup.GET("/books", exportHandler.ExportBooks)
+		}
+
+		leaderboardGroup := apiV1.Group("/leaderboards")
+		leaderboardGroup.Use(middleware.AuthMiddleware())
+		{
+			leaderboardGroup.GET("", leaderboardHandler.GetLeaderboard)
+		}
+
+		goalGroup := apiV1.Group("/goals")
+		goalGroup.Use(middleware.AuthMiddleware())
+		{
+			goalGroup.GET("", goalHandler.GetGoalHistory)
+			goalGroup.GET("/minutes", minutesGoalHandler.GetMinutesGoals)
+			goalGroup.PUT("/minutes", minutesGoalHandler.SetMinutesGoal)
+			goalGroup.GET("/minutes/today", minutesGoalHandler.GetTodayProgress)
+			goalGroup.GET("/minutes/history", minutesGoalHandler.GetHistory)
+			goalGroup.DELETE("/minutes/:id", minutesGoalHandler.DeleteMinutesGoal)
+			goalGroup.GET("/:year", goalHandler.GetGoal)
+			goalGroup.PUT("/:year", goalHandler.SetGoal)
+			goalGroup.DELETE("/:year", goalHandler.DeleteGoal)
+		}
+
+		seriesGroup := apiV1.Group("/series")
+		seriesGroup.Use(middleware.AuthMiddleware())
+		{
+			seriesGroup.GET("", seriesHandler.GetSeriesList)
+			seriesGroup.POST("", seriesHandler.CreateSeries)
+			seriesGroup.GET("/:id", seriesHandler.GetSeries)
+			seriesGroup.PUT("/:id", seriesHandler.UpdateSeries)
+			seriesGroup.DELETE("/:id", seriesHandler.DeleteSeries)
+			seriesGroup.POST("/:id/books", seriesHandler.AddBook)
+			seriesGroup.DELETE("/:id/books/:bookId", seriesHandler.RemoveBook)
+			seriesGroup.PUT("/:id/order", seriesHandler.ReorderBooks)
+		}
+
+		workGroup := apiV1.Group("/works")
+		workGroup.Use(middleware.AuthMiddleware())
+		{
+			workGroup.GET("", workHandler.GetWorks)
+			workGroup.POST("", workHandler.CreateWork)
+			workGroup.GET("/:id", workHandler.GetWork)
+			workGroup.DELETE("/:id", workHandler.DeleteWork)
+			workGroup.POST("/:id/editions", workHandler.AddEdition)
+			workGroup.DELETE("/:id/editions/:bookId", workHandler.RemoveEdition)
+		}
+
+		usersGroup := apiV1.Group("/users")
+		{
+			usersGroup.GET("/me/settings", middleware.AuthMiddleware(), userHandler.GetSettings)
+			usersGroup.PUT("/me/settings", middleware.AuthMiddleware(), userHandler.UpdateSettings)
+			usersGroup.GET("/me/tokens", middleware.AuthMiddleware(), tokenHandler.GetTokens)
+			usersGroup.POST("/me/tokens", middleware.AuthMiddleware(), tokenHandler.CreateToken)
+			usersGroup.DELETE("/me/tokens/:id", middleware.AuthMiddleware(), tokenHandler.RevokeToken)
+			usersGroup.GET("/:id", middleware.OptionalAuthMiddleware(), userHandler.GetProfile)
+			usersGroup.GET("/:id/followers", userHandler.GetFollowers)
+			usersGroup.GET("/:id/following", userHandler.GetFollowing)
+			usersGroup.POST("/:id/follow", middleware.AuthMiddleware(), userHandler.Follow)
+			usersGroup.DELETE("/:id/follow", middleware.AuthMiddleware(), userHandler.Unfollow)
+		}
+
+		queueGroup := apiV1.Group("/queue")
+		queueGroup.Use(middleware.AuthMiddleware())
+		{
+			queueGroup.GET("", queueHandler.GetQueue)
+			queueGroup.POST("", queueHandler.AddBook)
+			queueGroup.POST("/pop", queueHandler.PopNext)
+			queueGroup.PUT("/:bookId", queueHandler.MoveBook)
+			queueGroup.POST("/:bookId/pin", queueHandler.PinBook)
+			queueGroup.DELETE("/:bookId", queueHandler.RemoveBook)
+		}
+
+		loanGroup := apiV1.Group("/loans")
+		loanGroup.Use(middleware.AuthMiddleware())
+		{
+			loanGroup.GET("/outstanding", loanHandler.GetOutstandingLoans)
+			loanGroup.GET("/overdue", loanHandler.GetOverdueLoans)
+			loanGroup.GET("/borrowed", loanHandler.GetBorrowedLoans)
+			loanGroup.PUT("/:id", loanHandler.UpdateLoan)
+			loanGroup.POST("/:id/return", loanHandler.ReturnLoan)
+			loanGroup.DELETE("/:id", loanHandler.DeleteLoan)
+		}
+
+		notificationGroup := apiV1.Group("/notifications")
+		notificationGroup.Use(middleware.AuthMiddleware())
+		{
+			notificationGroup.GET("", notificationHandler.GetNotifications)
+			notificationGroup.POST("/read-all", notificationHandler.MarkAllRead)
+			notificationGroup.POST("/:id/read", notificationHandler.MarkRead)
+		}
+
+		// e-reader apps can't send a JWT, the catalog takes HTTP Basic and personal tokens
+		opdsGroup := apiV1.Group("/opds")
+		opdsGroup.Use(middleware.ReaderAuthMiddleware(deps.TokenService))
+		{
+			opdsGroup.GET("", opdsHandler.GetRoot)
+			opdsGroup.GET("/status", opdsHandler.GetStatuses)
+			opdsGroup.GET("/shelves", opdsHandler.GetShelves)
+			opdsGroup.GET("/authors", opdsHandler.GetAuthors)
+			opdsGroup.GET("/books", opdsHandler.GetBooks)
+			opdsGroup.GET("/opensearch.xml", opdsHandler.GetOpenSearch)
+		}
+
+		statsGroup := apiV1.Group("/stats")
+		statsGroup.Use(middleware.AuthMiddleware())
+		{
+			statsGroup.GET("/library", statsHandler.GetLibraryStats)
+		}
+
+		apiV1.GET("/recommendations", middleware.AuthMiddleware(), recommendationHandler.GetRecommendations)
+
 		searchGroup := apiV1.Group("/search")
+		searchGroup.Use(middleware.OptionalAuthMiddleware())
 		searchGroup.GET("", logHandler.SearchBook)
 
 		forumGroup := apiV1.Group("/forum")
@@ -83,7 +288,21 @@ func NewRouter(deps HandlerDependencies) *gin.Engine {
 		ReadGroup := apiV1.Group("/readtime")
 		{
 			ReadGroup.POST("", middleware.AuthMiddleware(), readHandler.CreateReadTime)
+			ReadGroup.POST("/batch", middleware.AuthMiddleware(), readHandler.UploadReadTimes)
+			ReadGroup.GET("/sync", middleware.AuthMiddleware(), readHandler.SyncReadTimes)
 			ReadGroup.GET("/weekly", middleware.AuthMiddleware(), readHandler.GetWeeklyReadTime)
+			ReadGroup.GET("/stats", middleware.AuthMiddleware(), readHandler.GetReadTimeStats)
+			ReadGroup.GET("/streaks", middleware.AuthMiddleware(), readHandler.GetReadingStreaks)
+			ReadGroup.GET("/timer", middleware.AuthMiddleware(), timerHandler.GetTimer)
+			ReadGroup.POST("/timer/start", middleware.AuthMiddleware(), timerHandler.StartTimer)
+			ReadGroup.POST("/timer/pause", middleware.AuthMiddleware(), timerHandler.PauseTimer)
+			ReadGroup.POST("/timer/resume", middleware.AuthMiddleware(), timerHandler.ResumeTimer)
+			ReadGroup.POST("/timer/stop", middleware.AuthMiddleware(), timerHandler.StopTimer)
+			ReadGroup.DELETE("/timer", middleware.AuthMiddleware(), timerHandler.DiscardTimer)
+			ReadGroup.GET("", middleware.AuthMiddleware(), readHandler.GetReadTimes)
+			ReadGroup.GET("/:id", middleware.AuthMiddleware(), readHandler.GetReadTime)
+			ReadGroup.PUT("/:id", middleware.AuthMiddleware(), readHandler.UpdateReadTime)
+			ReadGroup.DELETE("/:id", middleware.AuthMiddleware(), readHandler.DeleteReadTime)
 		}
 
 		ChatGroup := apiV1.Group("/chat")
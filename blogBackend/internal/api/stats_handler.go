@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsHandler struct {
+	statsService service.StatsService
+}
+
+func NewStatsHandler(svc service.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: svc}
+}
+
+// GetLibraryStats accepts ?from=YYYY-MM-DD&to=YYYY-MM-DD, both optional.
+func (h *StatsHandler) GetLibraryStats(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var userIDInt int
+	switch v := userID.(type) {
+	case float64:
+		userIDInt = int(v)
+	case int:
+		userIDInt = v
+	case uint:
+		userIDInt = int(v)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	stats, err := h.statsService.GetLibraryStats(userIDInt, c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
@@ -0,0 +1,17 @@
+package api
+
+import "github.com/gin-gonic/gin"
+
+// viewerID is the caller's user ID on routes behind OptionalAuthMiddleware, 0 for anonymous visitors
+func viewerID(c *gin.Context) int {
+	switch v := c.Value("userID").(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case uint:
+		return int(v)
+	default:
+		return 0
+	}
+}
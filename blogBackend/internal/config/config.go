@@ -41,6 +41,26 @@ type Config struct {
 
 	//DEEPSEEK API
 	OPENAI_API_KEY string
+
+	// blob storage configuration, BLOB_BACKEND is "local" or "s3"
+	BLOB_BACKEND   string
+	BLOB_LOCAL_DIR string
+	S3_ENDPOINT    string
+	S3_REGION      string
+	S3_BUCKET      string
+	S3_ACCESS_KEY  string
+	S3_SECRET_KEY  string
+
+	// background job configuration
+	RECOMMENDATION_REFRESH_INTERVAL string
+	LOAN_OVERDUE_CHECK_INTERVAL     string
+	READ_TIMER_CHECK_INTERVAL       string
+	// READ_TIMER_MAX_DURATION is how long a reading timer may run or stay paused before it is closed
+	READ_TIMER_MAX_DURATION string
+
+	// cache configuration
+	STATS_CACHE_TTL       string
+	LEADERBOARD_CACHE_TTL string
 }
 
 var (
@@ -71,12 +91,27 @@ func LoadConfig() {
 			ENVIRONMENT:           getEnvWithDefault("ENVIRONMENT", "development"),
 			LOG_LEVEL:             getEnvWithDefault("LOG_LEVEL", "info"),
 			CORS_ALLOWED_ORIGINS:  getEnvWithDefault("CORS_ALLOWED_ORIGINS", "http://localhost:3000"),
-			CORS_ALLOWED_METHODS:  getEnvWithDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"),
+			CORS_ALLOWED_METHODS:  getEnvWithDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
 			CORS_ALLOWED_HEADERS:  getEnvWithDefault("CORS_ALLOWED_HEADERS", "Content-Type,Authorization"),
 			EXTERNAL_API_BASE_URL: os.Getenv("EXTERNAL_API_BASE_URL"),
 			EXTERNAL_API_KEY:      os.Getenv("EXTERNAL_API_KEY"),
 			BCRYPT_COST:           bcryptCost,
 			OPENAI_API_KEY:        os.Getenv("OPENAI_API_KEY"),
+
+			BLOB_BACKEND:   getEnvWithDefault("BLOB_BACKEND", "local"),
+			BLOB_LOCAL_DIR: getEnvWithDefault("BLOB_LOCAL_DIR", "./uploads"),
+			S3_ENDPOINT:    os.Getenv("S3_ENDPOINT"),
+			S3_REGION:      getEnvWithDefault("S3_REGION", "us-east-1"),
+			S3_BUCKET:      os.Getenv("S3_BUCKET"),
+			S3_ACCESS_KEY:  os.Getenv("S3_ACCESS_KEY"),
+			S3_SECRET_KEY:  os.Getenv("S3_SECRET_KEY"),
+
+			RECOMMENDATION_REFRESH_INTERVAL: getEnvWithDefault("RECOMMENDATION_REFRESH_INTERVAL", "6h"),
+			LOAN_OVERDUE_CHECK_INTERVAL:     getEnvWithDefault("LOAN_OVERDUE_CHECK_INTERVAL", "1h"),
+			READ_TIMER_CHECK_INTERVAL:       getEnvWithDefault("READ_TIMER_CHECK_INTERVAL", "5m"),
+			READ_TIMER_MAX_DURATION:         getEnvWithDefault("READ_TIMER_MAX_DURATION", "4h"),
+			STATS_CACHE_TTL:                 getEnvWithDefault("STATS_CACHE_TTL", "5m"),
+			LEADERBOARD_CACHE_TTL:           getEnvWithDefault("LEADERBOARD_CACHE_TTL", "10m"),
 		}
 
 		if cfg.JWT_SECRET == "" {
@@ -1,9 +1,26 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Book status values
+const (
+	BookStatusWantToRead   = "Want to Read"
+	BookStatusReading      = "Reading"
+	BookStatusRead         = "Read"
+	BookStatusDidNotFinish = "Did Not Finish"
+)
+
+// Who can see a book log besides its owner, followers only count when the owner follows them back
+const (
+	VisibilityPrivate   = "private"
+	VisibilityFollowers = "followers"
+	VisibilityPublic    = "public"
+)
+
 type BookLog struct {
 	gorm.Model
 	// Getting from external API
@@ -11,11 +28,12 @@ type BookLog struct {
 	Author      string `json:"author" gorm:"type:varchar(100)"`
 	Description string `json:"description" gorm:"type:text"`
 	PublishedAt string `json:"published_at" gorm:"type:varchar(20)"`
-	ISBN        string `json:"isbn" gorm:"type:varchar(20);unique"`
+	ISBN        string `json:"isbn" gorm:"type:varchar(20);index"`
 	Category    string `json:"category" gorm:"type:varchar(50)"`
 	Rating      int    `json:"rating" gorm:"type:int;default:0"`
 	Review      string `json:"review" gorm:"type:text"`
 	CoverUrl    string `json:"cover_url" gorm:"type:varchar(255)"`
+	PageCount   int    `json:"page_count" gorm:"type:int;default:0"`
 
 	UserID uint    `json:"user_id" gorm:"not null;index"`
 	User   UserLog `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
@@ -25,4 +43,17 @@ type BookLog struct {
 
 	// Book status
 	Status string `json:"status" gorm:"type:varchar(20);index"`
+	// Visibility is one of the Visibility constants, new book logs get the owner's default
+	Visibility string `json:"visibility" gorm:"type:varchar(10);not null;default:'private';index"`
+	// FinishedAt is set when the book is marked as read
+	FinishedAt *time.Time `json:"finished_at" gorm:"index"`
+
+	// user defined shelves
+	Tags []BookTag `json:"tags" gorm:"foreignKey:BookLogID"`
+
+	// SeriesPosition orders the book within its series, editions of one volume share a position
+	SeriesID       *uint    `json:"series_id" gorm:"index"`
+	SeriesPosition *float64 `json:"series_position"`
+	// WorkID groups this book log with the other editions of the same book
+	WorkID *uint `json:"work_id" gorm:"index"`
 }
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FieldChange is one changed field of a book log, values are stored as JSON so any column type fits
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// BookLogRevision records one change to a book log. Revisions are append-only, a revert adds a new revision.
+type BookLogRevision struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	BookLogID uint          `json:"book_log_id" gorm:"not null;index"`
+	UserID    uint          `json:"user_id" gorm:"not null;index"`
+	Changes   []FieldChange `json:"changes" gorm:"type:jsonb;serializer:json;not null"`
+	// RevertOf is the revision the book was restored to, if this one was created by a revert
+	RevertOf  *uint     `json:"revert_of"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
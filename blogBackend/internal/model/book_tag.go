@@ -0,0 +1,9 @@
+package model
+
+// BookTag puts a book log on a user defined shelf, a book can be on any number of shelves
+type BookTag struct {
+	ID        uint    `json:"-" gorm:"primaryKey"`
+	BookLogID uint    `json:"-" gorm:"not null;uniqueIndex:idx_book_tag"`
+	BookLog   BookLog `json:"-" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name      string  `json:"name" gorm:"type:varchar(50);not null;index;uniqueIndex:idx_book_tag"`
+}
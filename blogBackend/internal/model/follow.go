@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Follow means Follower sees the books Followee shares with followers
+type Follow struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	FollowerID uint      `json:"follower_id" gorm:"not null;uniqueIndex:idx_follow"`
+	Follower   UserLog   `json:"-" gorm:"foreignKey:FollowerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FolloweeID uint      `json:"followee_id" gorm:"not null;uniqueIndex:idx_follow;index"`
+	Followee   UserLog   `json:"-" gorm:"foreignKey:FolloweeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Highlight is a quote or note attached to a book log
+type Highlight struct {
+	gorm.Model
+	UserID    uint    `json:"user_id" gorm:"not null;index"`
+	User      UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	BookLogID uint    `json:"book_log_id" gorm:"not null;index"`
+	BookLog   BookLog `json:"book_log" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// where in the book, either a page number or a free-form location (e.g. Kindle location)
+	Page     *int   `json:"page" gorm:"type:int"`
+	Location string `json:"location" gorm:"type:varchar(50)"`
+
+	Quote string         `json:"quote" gorm:"type:text"`
+	Note  string         `json:"note" gorm:"type:text"`
+	Tags  []HighlightTag `json:"tags" gorm:"foreignKey:HighlightID"`
+}
+
+type HighlightTag struct {
+	ID          uint   `json:"-" gorm:"primaryKey"`
+	HighlightID uint   `json:"-" gorm:"not null;index;uniqueIndex:idx_highlight_tag"`
+	Name        string `json:"name" gorm:"type:varchar(50);not null;index;uniqueIndex:idx_highlight_tag"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Import job status
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Supported import sources
+const (
+	ImportSourceGoodreads  = "goodreads"
+	ImportSourceStoryGraph = "storygraph"
+)
+
+type ImportJob struct {
+	gorm.Model
+	UserID uint    `json:"user_id" gorm:"not null;index"`
+	User   UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Source string  `json:"source" gorm:"type:varchar(20)"`
+	Status string  `json:"status" gorm:"type:varchar(20);index"`
+	Error  string  `json:"error" gorm:"type:text"`
+
+	// progress counters
+	TotalRows     int `json:"total_rows" gorm:"default:0"`
+	ProcessedRows int `json:"processed_rows" gorm:"default:0"`
+	ImportedRows  int `json:"imported_rows" gorm:"default:0"`
+	SkippedRows   int `json:"skipped_rows" gorm:"default:0"`
+	FailedRows    int `json:"failed_rows" gorm:"default:0"`
+
+	RowErrors []ImportRowError `json:"row_errors" gorm:"foreignKey:ImportJobID"`
+}
+
+// ImportRowError records why a single CSV row could not be imported.
+type ImportRowError struct {
+	gorm.Model
+	ImportJobID uint   `json:"import_job_id" gorm:"not null;index"`
+	Line        int    `json:"line"`
+	Title       string `json:"title" gorm:"type:varchar(255)"`
+	Message     string `json:"message" gorm:"type:text"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Loan is a book lent to someone, either a registered user or a free-text name.
+// A book log has at most one outstanding loan.
+type Loan struct {
+	gorm.Model
+	UserID    uint    `json:"user_id" gorm:"not null;index"`
+	User      UserLog `json:"lender" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	BookLogID uint    `json:"book_log_id" gorm:"not null;uniqueIndex:idx_loan_outstanding,where:returned_at IS NULL AND deleted_at IS NULL"`
+	BookLog   BookLog `json:"book" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	BorrowerUserID *uint    `json:"borrower_user_id" gorm:"index"`
+	BorrowerUser   *UserLog `json:"borrower_user,omitempty" gorm:"foreignKey:BorrowerUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// BorrowerName is always set, for registered borrowers it is their user name at the time of lending
+	BorrowerName string `json:"borrower_name" gorm:"type:varchar(100);not null"`
+
+	LentAt     time.Time  `json:"lent_at"`
+	DueAt      *time.Time `json:"due_at" gorm:"index"`
+	ReturnedAt *time.Time `json:"returned_at"`
+	// OverdueNotifiedAt is set once the overdue notification went out, so it is sent only once
+	OverdueNotifiedAt *time.Time `json:"-"`
+}
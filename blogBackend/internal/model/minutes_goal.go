@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+const (
+	MinutesGoalDaily  = "daily"
+	MinutesGoalWeekly = "weekly"
+)
+
+// MinutesGoal is a daily or weekly reading-minutes target. A goal is in effect from EffectiveFrom
+// until the user's next goal starts, so past days stay judged by the goal they had.
+type MinutesGoal struct {
+	ID     uint    `json:"id" gorm:"primaryKey"`
+	UserID uint    `json:"user_id" gorm:"not null;uniqueIndex:idx_minutes_goal_user_from"`
+	User   UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Period string  `json:"period" gorm:"type:varchar(10);not null"`
+	// TargetMinutes 0 ends the previous goal without starting a new one
+	TargetMinutes int `json:"target_minutes" gorm:"not null"`
+	// EffectiveFrom is a day in the user's timezone formatted YYYY-MM-DD, so it sorts as text
+	EffectiveFrom string    `json:"effective_from" gorm:"type:varchar(10);not null;uniqueIndex:idx_minutes_goal_user_from"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Notification types
+const (
+	NotificationLoanOverdue         = "loan_overdue"
+	NotificationLoanOverdueBorrower = "loan_overdue_borrower"
+)
+
+// Notification is a message for a user, shown until it is marked as read
+type Notification struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	UserID  uint   `json:"-" gorm:"not null;index:idx_notification_user_read"`
+	Type    string `json:"type" gorm:"type:varchar(30);not null"`
+	Message string `json:"message" gorm:"type:text;not null"`
+	// EntityID points at what the notification is about, e.g. the loan for loan notifications
+	EntityID  *uint      `json:"entity_id"`
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notification_user_read"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PersonalToken is a long-lived credential for clients that can't log in with a JWT, such as e-reader apps.
+// Only a hash of the token is stored, the token itself is shown once when it is created.
+type PersonalToken struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	UserID    uint    `json:"-" gorm:"not null;index"`
+	User      UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name      string  `json:"name" gorm:"type:varchar(100);not null"`
+	TokenHash string  `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	// Prefix is the start of the token, enough to tell tokens apart in a list
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// QueueEntry places a want-to-read book log in its owner's "up next" queue.
+// Entries are ordered by SortKey, a lexicographic rank, so moving one book rewrites only its own row.
+type QueueEntry struct {
+	ID        uint    `json:"-" gorm:"primaryKey"`
+	UserID    uint    `json:"user_id" gorm:"not null;index:idx_queue_user_sort_key"`
+	BookLogID uint    `json:"book_log_id" gorm:"not null;uniqueIndex"`
+	BookLog   BookLog `json:"book" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// SortKey only uses the letters a-z and never ends with "a", so there is always room between two keys
+	SortKey   string    `json:"sort_key" gorm:"type:varchar(255);not null;index:idx_queue_user_sort_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
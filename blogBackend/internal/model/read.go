@@ -1,11 +1,29 @@
 package model
+
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
+
+// Read is one reading session
 type Read struct {
 	gorm.Model
-	Time int    `json:"time" gorm:"not null"`
-	UserID  uint  `json:"user_id" gorm:"not null;index"`
-	User    UserLog `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-}
+	// Time is the length of the session in minutes
+	Time   int     `json:"time" gorm:"not null"`
+	UserID uint    `json:"user_id" gorm:"not null;index;uniqueIndex:idx_read_user_client_id,priority:1"`
+	User   UserLog `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 
+	// BookLogID is the book read in the session, sessions don't have to be linked to a book
+	BookLogID *uint    `json:"book_log_id" gorm:"index"`
+	BookLog   *BookLog `json:"book,omitempty" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+
+	// StartedAt is when the session happened as the client reported it, sessions are grouped by it
+	StartedAt time.Time  `json:"started_at" gorm:"index"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Pages     int        `json:"pages" gorm:"not null;default:0"`
+
+	// ClientID is the lowercase UUID an offline client generated for the session, uploading
+	// it again finds the stored session instead of creating a second one
+	ClientID *string `json:"client_id" gorm:"type:varchar(36);uniqueIndex:idx_read_user_client_id,priority:2"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ReadTimer is a reading session that is still going on, a user has at most one. The time read
+// so far is AccumulatedSeconds plus the time since ResumedAt while the timer runs.
+type ReadTimer struct {
+	ID     uint    `json:"id" gorm:"primaryKey"`
+	UserID uint    `json:"user_id" gorm:"not null;uniqueIndex"`
+	User   UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	BookLogID *uint    `json:"book_log_id" gorm:"index"`
+	BookLog   *BookLog `json:"book,omitempty" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+
+	StartedAt time.Time `json:"started_at" gorm:"not null;index"`
+	// ResumedAt is when the timer last started running, nil while it is paused
+	ResumedAt          *time.Time `json:"resumed_at"`
+	PausedAt           *time.Time `json:"paused_at"`
+	AccumulatedSeconds int        `json:"-" gorm:"not null;default:0"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// ReadingGoal is a yearly challenge, e.g. "40 books in 2026" or "10,000 pages this year".
+// Either target may be zero when the user only tracks the other one.
+type ReadingGoal struct {
+	gorm.Model
+	UserID      uint    `json:"user_id" gorm:"not null;uniqueIndex:idx_reading_goal_user_year"`
+	User        UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Year        int     `json:"year" gorm:"not null;uniqueIndex:idx_reading_goal_user_year"`
+	TargetBooks int     `json:"target_books" gorm:"default:0"`
+	TargetPages int     `json:"target_pages" gorm:"default:0"`
+}
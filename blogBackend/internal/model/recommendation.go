@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// Recommendation is a precomputed "read next" suggestion, rebuilt by the recommendation job
+type Recommendation struct {
+	ID     uint    `json:"id" gorm:"primarykey"`
+	UserID uint    `json:"user_id" gorm:"not null;index"`
+	User   UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	Title    string `json:"title" gorm:"type:varchar(100)"`
+	Author   string `json:"author" gorm:"type:varchar(100)"`
+	ISBN     string `json:"isbn" gorm:"type:varchar(20)"`
+	Category string `json:"category" gorm:"type:varchar(50)"`
+	CoverUrl string `json:"cover_url" gorm:"type:varchar(255)"`
+
+	Score       float64   `json:"score"`
+	Explanation string    `json:"explanation" gorm:"type:text"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
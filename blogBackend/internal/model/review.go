@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review is a published BookLog review. The text and rating stay on the book log
+// (MyComment, MyRating); this row makes it public and keeps the social counters.
+type Review struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	BookLogID uint    `json:"book_log_id" gorm:"not null;uniqueIndex"`
+	BookLog   BookLog `json:"-" gorm:"foreignKey:BookLogID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uint    `json:"user_id" gorm:"not null;index"`
+	User      UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	PublishedAt  time.Time `json:"published_at" gorm:"not null;index"`
+	LikeCount    int       `json:"like_count" gorm:"not null;default:0;index"`
+	CommentCount int       `json:"comment_count" gorm:"not null;default:0"`
+}
+
+type ReviewLike struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	ReviewID  uint      `json:"review_id" gorm:"not null;uniqueIndex:idx_review_like"`
+	Review    Review    `json:"-" gorm:"foreignKey:ReviewID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_review_like;index"`
+	User      UserLog   `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type ReviewComment struct {
+	gorm.Model
+	ReviewID uint    `json:"review_id" gorm:"not null;index"`
+	Review   Review  `json:"-" gorm:"foreignKey:ReviewID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID   uint    `json:"user_id" gorm:"not null;index"`
+	User     UserLog `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Content  string  `json:"content" gorm:"type:text;not null"`
+}
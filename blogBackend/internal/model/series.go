@@ -0,0 +1,26 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Series is an ordered sequence of books, e.g. "The Expanse". Books reference it with a position
+// that may be fractional for novellas published between two volumes.
+type Series struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_series_user_name"`
+	User        UserLog   `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null;uniqueIndex:idx_series_user_name"`
+	Description string    `json:"description" gorm:"type:text"`
+	Books       []BookLog `json:"books,omitempty" gorm:"foreignKey:SeriesID;constraint:OnDelete:SET NULL;"`
+}
+
+// Work groups the editions of one book, e.g. the hardcover and the audiobook of the same title.
+type Work struct {
+	gorm.Model
+	UserID   uint      `json:"user_id" gorm:"not null;index"`
+	User     UserLog   `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Title    string    `json:"title" gorm:"type:varchar(100);not null"`
+	Author   string    `json:"author" gorm:"type:varchar(100)"`
+	Editions []BookLog `json:"editions,omitempty" gorm:"foreignKey:WorkID;constraint:OnDelete:SET NULL;"`
+}
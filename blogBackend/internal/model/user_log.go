@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -8,8 +11,46 @@ type UserLog struct {
 	gorm.Model
 	UserName string `json:"user_name" gorm:"type:varchar(50);not null"`
 	Email    string `json:"email" gorm:"type:varchar(100);unique;not null"`
-	Password string `json:"password" gorm:"type:varchar(100);not null"`
+	Password string `json:"-" gorm:"type:varchar(100);not null"`
+
+	// DefaultVisibility is applied to new book logs that don't set one
+	DefaultVisibility string `json:"-" gorm:"type:varchar(10);not null;default:'private'"`
+	// Timezone is the IANA name reading days are counted in
+	Timezone string `json:"-" gorm:"type:varchar(64);not null;default:'UTC'"`
+	// StreakMinMinutes is how long the user has to read on a day for it to count towards a streak
+	StreakMinMinutes int `json:"-" gorm:"not null;default:1"`
+	// LeaderboardOptOut keeps the user off the leaderboards of the readers they follow back
+	LeaderboardOptOut bool `json:"-" gorm:"not null;default:false"`
 
 	// 反向关联 - 用户添加的所有图书
 	BookLogs []BookLog `json:"book_logs" gorm:"foreignKey:UserID"`
 }
+
+// PublicUser is the projection of a user that is safe to show to other users
+type PublicUser struct {
+	ID       uint   `json:"id"`
+	UserName string `json:"user_name"`
+}
+
+func (u UserLog) Public() PublicUser {
+	return PublicUser{ID: u.ID, UserName: u.UserName}
+}
+
+// SelfUser is how a user sees their own account, keyed like the user model was before it only showed
+// the public projection
+type SelfUser struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	UserName  string    `json:"user_name"`
+	Email     string    `json:"email"`
+}
+
+func (u UserLog) Self() SelfUser {
+	return SelfUser{ID: u.ID, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, UserName: u.UserName, Email: u.Email}
+}
+
+// MarshalJSON only ever exposes the public projection, so a preloaded user can't leak email or password
+func (u UserLog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.Public())
+}
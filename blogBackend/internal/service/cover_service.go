@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoders for uploads
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"project/internal/model"
+	"project/internal/store"
+	"regexp"
+	"strings"
+)
+
+// MaxCoverSize is the largest cover upload accepted, in bytes
+const MaxCoverSize = 5 << 20
+
+// maxCoverPixels guards against decompression bombs, checked before decoding
+const maxCoverPixels = 40_000_000
+
+// CoverSizes are the thumbnail widths generated for every upload; "original" keeps the re-encoded upload
+var CoverSizes = map[string]int{
+	"small":  150,
+	"medium": 300,
+	"large":  600,
+}
+
+const CoverSizeOriginal = "original"
+
+// CoverURLPrefix is where covers are served, see CoverHandler
+const CoverURLPrefix = "/api/v1/covers/"
+
+var allowedCoverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var coverIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+type CoverService interface {
+	// UploadCover stores a cover and its thumbnails and points the book log's CoverUrl at the medium size.
+	UploadCover(bookID int, userID int, r io.Reader) (*model.BookLog, map[string]string, error)
+
+	// SaveCover stores a cover and its thumbnails without attaching it to a book log and returns the URL of every size.
+	SaveCover(r io.Reader) (map[string]string, error)
+	OpenCover(coverID string, size string) (io.ReadCloser, *store.BlobInfo, error)
+}
+
+type coverService struct {
+	blobStore    store.BlobStore
+	bookLogStore store.BookLogStore
+}
+
+func NewCoverService(blobStore store.BlobStore, bookLogStore store.BookLogStore) CoverService {
+	return &coverService{blobStore: blobStore, bookLogStore: bookLogStore}
+}
+
+func coverKey(coverID, size string) string {
+	return "covers/" + coverID + "-" + size + ".jpg"
+}
+
+// CoverURL is the download URL of one size of a cover
+func CoverURL(coverID, size string) string {
+	return CoverURLPrefix + coverID + "/" + size
+}
+
+func (s *coverService) UploadCover(bookID int, userID int, r io.Reader) (*model.BookLog, map[string]string, error) {
+	if _, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID); err != nil {
+		return nil, nil, err
+	}
+	urls, err := s.SaveCover(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.bookLogStore.PatchLog(bookID, userID, map[string]interface{}{"cover_url": urls["medium"]}); err != nil {
+		return nil, nil, err
+	}
+	book, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return book, urls, nil
+}
+
+func (s *coverService) SaveCover(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxCoverSize {
+		return nil, invalidInput("cover image must be at most %d MB", MaxCoverSize>>20)
+	}
+	// trust the bytes, not the client supplied content type
+	if contentType := http.DetectContentType(data); !allowedCoverTypes[contentType] {
+		return nil, invalidInput("cover must be a JPEG, PNG or GIF image, got %s", contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalidInput("cover image could not be read: %v", err)
+	}
+	if cfg.Width*cfg.Height > maxCoverPixels {
+		return nil, invalidInput("cover image is too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalidInput("cover image could not be decoded: %v", err)
+	}
+
+	// covers are content addressed, the same image is stored once and URLs can be cached forever
+	sum := sha256.Sum256(data)
+	coverID := hex.EncodeToString(sum[:16])
+
+	src := flattenImage(img)
+	urls := map[string]string{}
+	if err := s.putJPEG(coverKey(coverID, CoverSizeOriginal), src); err != nil {
+		return nil, err
+	}
+	urls[CoverSizeOriginal] = CoverURL(coverID, CoverSizeOriginal)
+
+	// largest first so every thumbnail is scaled from the next bigger one
+	current := src
+	for _, size := range []string{"large", "medium", "small"} {
+		current = scaleToWidth(current, CoverSizes[size])
+		if err := s.putJPEG(coverKey(coverID, size), current); err != nil {
+			return nil, err
+		}
+		urls[size] = CoverURL(coverID, size)
+	}
+	return urls, nil
+}
+
+func (s *coverService) OpenCover(coverID string, size string) (io.ReadCloser, *store.BlobInfo, error) {
+	if _, ok := CoverSizes[size]; !ok && size != CoverSizeOriginal {
+		return nil, nil, invalidInput("unknown cover size %q", size)
+	}
+	if !coverIDPattern.MatchString(coverID) {
+		return nil, nil, store.ErrBlobNotFound
+	}
+	return s.blobStore.Get(coverKey(coverID, size))
+}
+
+func (s *coverService) putJPEG(key string, img *image.RGBA) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+	return s.blobStore.Put(key, "image/jpeg", &buf, int64(buf.Len()))
+}
+
+// flattenImage converts any decoded image to RGBA on a white background, JPEG has no alpha channel
+func flattenImage(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// scaleToWidth downscales with a box filter, keeping the aspect ratio. Images are never upscaled.
+func scaleToWidth(src *image.RGBA, width int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= width || sw == 0 {
+		return src
+	}
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := (y + 1) * sh / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * sw / width
+			x1 := (x + 1) * sw / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := sy*src.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			j := y*dst.Stride + x*4
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// isCoverURL reports whether url points at a cover served by this API
+func isCoverURL(url string) bool {
+	return strings.HasPrefix(url, CoverURLPrefix)
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"errors"
+	"project/internal/model"
+	"project/internal/store"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a group of book logs is reported as duplicates
+const (
+	DuplicateReasonISBN        = "isbn"
+	DuplicateReasonTitleAuthor = "title_author"
+)
+
+const (
+	// minTitleSimilarity and minAuthorSimilarity are the fuzzy match thresholds, 1 is an exact match
+	minTitleSimilarity  = 0.85
+	minAuthorSimilarity = 0.8
+)
+
+type DuplicateGroup struct {
+	Reason string `json:"reason"`
+	// Score is 1 for ISBN matches and the lowest pairwise title similarity otherwise
+	Score float64         `json:"score"`
+	Books []model.BookLog `json:"books"`
+}
+
+type DuplicateService interface {
+	FindDuplicates(userID int) ([]DuplicateGroup, error)
+
+	// MergeBooks folds duplicateID into survivorID and returns the survivor.
+	MergeBooks(userID int, survivorID int, duplicateID int) (*model.BookLog, error)
+}
+
+type duplicateService struct {
+	bookLogStore store.BookLogStore
+}
+
+func NewDuplicateService(bookLogStore store.BookLogStore) DuplicateService {
+	return &duplicateService{bookLogStore: bookLogStore}
+}
+
+// duplicateCandidate is a book log with its normalized match keys
+type duplicateCandidate struct {
+	book   model.BookLog
+	isbn   string
+	title  string
+	author string
+}
+
+func (s *duplicateService) FindDuplicates(userID int) ([]DuplicateGroup, error) {
+	var candidates []duplicateCandidate
+	err := s.bookLogStore.IterateBookLogs(userID, exportBatchSize, func(books []model.BookLog) error {
+		for _, book := range books {
+			candidates = append(candidates, duplicateCandidate{
+				book:   book,
+				isbn:   normalizeISBN(book.ISBN),
+				title:  normalizeTitle(book.Title),
+				author: normalizeAuthor(book.Author),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// union-find over matching pairs, a group can be linked by a mix of ISBN and title matches
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	reason := make(map[int]string)
+	score := make(map[int]float64)
+	link := func(a, b int, why string, similarity float64) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+		root := find(a)
+		merged := similarity
+		if s, ok := score[ra]; ok && s < merged {
+			merged = s
+		}
+		if s, ok := score[rb]; ok && s < merged {
+			merged = s
+		}
+		score[root] = merged
+		if reason[ra] == DuplicateReasonTitleAuthor || reason[rb] == DuplicateReasonTitleAuthor || why == DuplicateReasonTitleAuthor {
+			reason[root] = DuplicateReasonTitleAuthor
+		} else {
+			reason[root] = DuplicateReasonISBN
+		}
+	}
+
+	// blocking keeps this far from quadratic: books are only compared when they share an ISBN,
+	// the start of the title or the author's surname
+	byISBN := make(map[string][]int)
+	blocks := make(map[string][]int)
+	for i, c := range candidates {
+		if c.isbn != "" {
+			byISBN[c.isbn] = append(byISBN[c.isbn], i)
+		}
+		if c.title == "" {
+			continue
+		}
+		blocks["t:"+truncate(c.title, 4)] = append(blocks["t:"+truncate(c.title, 4)], i)
+		if fields := strings.Fields(c.author); len(fields) > 0 {
+			surname := fields[len(fields)-1]
+			blocks["a:"+surname] = append(blocks["a:"+surname], i)
+		}
+	}
+	for _, ids := range byISBN {
+		for _, id := range ids[1:] {
+			link(ids[0], id, DuplicateReasonISBN, 1)
+		}
+	}
+	for _, ids := range blocks {
+		for x, a := range ids {
+			for _, b := range ids[x+1:] {
+				if find(a) == find(b) {
+					continue
+				}
+				ca, cb := candidates[a], candidates[b]
+				// different ISBNs are different editions on purpose
+				if ca.isbn != "" && cb.isbn != "" && ca.isbn != cb.isbn {
+					continue
+				}
+				titleSim := similarity(ca.title, cb.title)
+				if titleSim < minTitleSimilarity {
+					continue
+				}
+				if ca.author != "" && cb.author != "" && similarity(ca.author, cb.author) < minAuthorSimilarity {
+					continue
+				}
+				link(a, b, DuplicateReasonTitleAuthor, titleSim)
+			}
+		}
+	}
+
+	grouped := make(map[int][]model.BookLog)
+	for i, c := range candidates {
+		root := find(i)
+		grouped[root] = append(grouped[root], c.book)
+	}
+	groups := []DuplicateGroup{}
+	for root, books := range grouped {
+		if len(books) < 2 {
+			continue
+		}
+		sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+		groups = append(groups, DuplicateGroup{Reason: reason[root], Score: score[root], Books: books})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Books[0].ID < groups[j].Books[0].ID })
+	return groups, nil
+}
+
+func (s *duplicateService) MergeBooks(userID int, survivorID int, duplicateID int) (*model.BookLog, error) {
+	if survivorID == duplicateID {
+		return nil, invalidInput("a book log cannot be merged into itself")
+	}
+	survivor, err := s.bookLogStore.GetBookByIDAndUserID(survivorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	duplicate, err := s.bookLogStore.GetBookByIDAndUserID(duplicateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.bookLogStore.MergeBookLogs(userID, survivor.ID, mergeBookFields(survivor, duplicate), duplicate.ID)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, invalidInput("both books are lent out, return one of them before merging")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.bookLogStore.GetBookByIDAndUserID(survivorID, userID)
+}
+
+// mergeBookFields returns the survivor columns to overwrite so the survivor keeps the richer metadata.
+// The survivor wins whenever both books have a value, except where more is clearly better.
+func mergeBookFields(survivor, duplicate *model.BookLog) map[string]interface{} {
+	fields := make(map[string]interface{})
+	fillEmpty := func(column, current, other string) {
+		if strings.TrimSpace(current) == "" && strings.TrimSpace(other) != "" {
+			fields[column] = other
+		}
+	}
+	fillEmpty("author", survivor.Author, duplicate.Author)
+	fillEmpty("published_at", survivor.PublishedAt, duplicate.PublishedAt)
+	fillEmpty("isbn", survivor.ISBN, duplicate.ISBN)
+	fillEmpty("category", survivor.Category, duplicate.Category)
+	fillEmpty("cover_url", survivor.CoverUrl, duplicate.CoverUrl)
+	fillEmpty("review", survivor.Review, duplicate.Review)
+
+	if len(duplicate.Description) > len(survivor.Description) {
+		fields["description"] = duplicate.Description
+	}
+	if survivor.Rating == 0 && duplicate.Rating > 0 {
+		fields["rating"] = duplicate.Rating
+	}
+	if survivor.PageCount == 0 && duplicate.PageCount > 0 {
+		fields["page_count"] = duplicate.PageCount
+	}
+	if (survivor.MyRating == nil || *survivor.MyRating == 0) && duplicate.MyRating != nil && *duplicate.MyRating > 0 {
+		fields["my_rating"] = *duplicate.MyRating
+	}
+
+	if survivor.SeriesID == nil && duplicate.SeriesID != nil {
+		fields["series_id"] = *duplicate.SeriesID
+		if duplicate.SeriesPosition != nil {
+			fields["series_position"] = *duplicate.SeriesPosition
+		}
+	}
+	if survivor.WorkID == nil && duplicate.WorkID != nil {
+		fields["work_id"] = *duplicate.WorkID
+	}
+
+	// personal notes are never thrown away
+	mine, theirs := strings.TrimSpace(survivor.MyComment), strings.TrimSpace(duplicate.MyComment)
+	switch {
+	case theirs == "" || theirs == mine:
+	case mine == "":
+		fields["my_comment"] = duplicate.MyComment
+	default:
+		fields["my_comment"] = survivor.MyComment + "\n\n" + duplicate.MyComment
+	}
+
+	// progress: keep the furthest status, and the earliest date the book was finished
+	if statusRank(duplicate.Status) > statusRank(survivor.Status) {
+		fields["status"] = duplicate.Status
+	}
+	if duplicate.FinishedAt != nil && (survivor.FinishedAt == nil || duplicate.FinishedAt.Before(*survivor.FinishedAt)) {
+		fields["finished_at"] = *duplicate.FinishedAt
+	}
+	if status, ok := fields["status"]; ok && status == model.BookStatusRead && survivor.FinishedAt == nil && duplicate.FinishedAt == nil {
+		fields["finished_at"] = time.Now()
+	}
+	return fields
+}
+
+func statusRank(status string) int {
+	switch status {
+	case model.BookStatusRead:
+		return 3
+	case model.BookStatusReading:
+		return 2
+	case model.BookStatusDidNotFinish:
+		return 1
+	default:
+		return 0
+	}
+}
+
+var (
+	// subtitles and series markers such as "Dune: Deluxe Edition" or "Dune (Dune Chronicles, #1)"
+	titleSuffixPattern = regexp.MustCompile(`\s*(\(.*\)|\[.*\]|:.*)$`)
+	leadingArticle     = regexp.MustCompile(`^(the|a|an) `)
+)
+
+func normalizeTitle(title string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+	if stripped := titleSuffixPattern.ReplaceAllString(title, ""); stripped != "" {
+		title = stripped
+	}
+	title = normalizeWords(title)
+	return leadingArticle.ReplaceAllString(title, "")
+}
+
+// normalizeAuthor folds "J.K. Rowling" and "J. K. Rowling" together and keeps only the first author
+func normalizeAuthor(author string) string {
+	author = strings.ToLower(author)
+	if i := strings.IndexAny(author, ",;&"); i >= 0 {
+		author = author[:i]
+	}
+	author = strings.ReplaceAll(author, ".", " ")
+	return normalizeWords(author)
+}
+
+// normalizeWords drops punctuation and collapses whitespace
+func normalizeWords(s string) string {
+	return strings.Join(strings.Fields(unsafeFileNameChars.ReplaceAllString(s, " ")), " ")
+}
+
+// similarity is 1 minus the Levenshtein distance relative to the longer string
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
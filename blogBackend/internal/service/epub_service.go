@@ -0,0 +1,364 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"project/internal/model"
+	"project/internal/store"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxEPUBSize is the largest EPUB upload accepted, in bytes
+const MaxEPUBSize = 50 << 20
+
+// maxOPFSize guards against compressed XML bombs inside the archive
+const maxOPFSize = 1 << 20
+
+// EPUBMetadata is what the package document (OPF) of an EPUB says about the book
+type EPUBMetadata struct {
+	Title       string   `json:"title"`
+	Creators    []string `json:"creators"`
+	ISBN        string   `json:"isbn"`
+	Description string   `json:"description"`
+	Subjects    []string `json:"subjects"`
+	PublishedAt string   `json:"published_at"`
+	// CoverPath is the cover image inside the archive, empty when the book declares none
+	CoverPath string `json:"cover_path"`
+}
+
+// EPUBImport is the book log filled from an EPUB. Book is not saved when the import is a preview.
+type EPUBImport struct {
+	Book     *model.BookLog    `json:"book"`
+	Metadata EPUBMetadata      `json:"metadata"`
+	Covers   map[string]string `json:"covers,omitempty"`
+	// Warnings explain metadata that was found but could not be used, e.g. an unsupported cover format
+	Warnings []string `json:"warnings"`
+}
+
+type EPUBService interface {
+	// ImportEPUB reads the metadata of an EPUB and stores its cover. It creates a book log with the given
+	// status (Want to Read when empty), or only returns the pre-filled book log when preview is set.
+	// A preview stores nothing, its metadata names the cover but the book log has no cover url yet.
+	ImportEPUB(userID int, r io.ReaderAt, size int64, status string, preview bool) (*EPUBImport, error)
+}
+
+type epubService struct {
+	bookLogStore store.BookLogStore
+	coverService CoverService
+}
+
+func NewEPUBService(bookLogStore store.BookLogStore, coverService CoverService) EPUBService {
+	return &epubService{bookLogStore: bookLogStore, coverService: coverService}
+}
+
+func (s *epubService) ImportEPUB(userID int, r io.ReaderAt, size int64, status string, preview bool) (*EPUBImport, error) {
+	if status == "" {
+		status = model.BookStatusWantToRead
+	}
+	if !isValidBookStatus(status) {
+		return nil, invalidInput("invalid status %q", status)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, invalidInput("the file is not an EPUB: %v", err)
+	}
+	metadata, err := parseEPUB(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EPUBImport{Metadata: *metadata, Warnings: []string{}}
+	book := &model.BookLog{
+		Title:       truncate(metadata.Title, 100),
+		Author:      truncate(strings.Join(metadata.Creators, ", "), 100),
+		ISBN:        metadata.ISBN,
+		Description: metadata.Description,
+		PublishedAt: truncate(metadata.PublishedAt, 20),
+		Status:      status,
+	}
+	if len(metadata.Subjects) > 0 {
+		book.Category = truncate(metadata.Subjects[0], 50)
+	}
+
+	// a broken cover should not cost the reader the rest of the metadata
+	if metadata.CoverPath != "" && !preview {
+		covers, err := s.saveCover(archive, metadata.CoverPath)
+		if err != nil {
+			result.Warnings = append(result.Warnings, "cover not imported: "+err.Error())
+		} else {
+			result.Covers = covers
+			book.CoverUrl = covers["medium"]
+		}
+	}
+	result.Book = book
+	if preview {
+		return result, nil
+	}
+
+	if book.Title == "" {
+		return nil, invalidInput("the EPUB has no title, create the book log from the preview instead")
+	}
+	if book.Status == model.BookStatusRead {
+		now := time.Now()
+		book.FinishedAt = &now
+	}
+	if err := s.bookLogStore.Create(userID, book); err != nil {
+		return nil, err
+	}
+	if result.Book, err = s.bookLogStore.GetBookByIDAndUserID(int(book.ID), userID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *epubService) saveCover(archive *zip.Reader, name string) (map[string]string, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return s.coverService.SaveCover(file)
+}
+
+// epubContainer is META-INF/container.xml, it points at the package document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// opfPackage is the part of the package document we read, Dublin Core elements are matched by local name
+type opfPackage struct {
+	Metadata struct {
+		Titles      []string        `xml:"title"`
+		Creators    []opfCreator    `xml:"creator"`
+		Identifiers []opfIdentifier `xml:"identifier"`
+		Description string          `xml:"description"`
+		Subjects    []string        `xml:"subject"`
+		Dates       []string        `xml:"date"`
+		Metas       []opfMeta       `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []opfItem `xml:"manifest>item"`
+}
+
+type opfCreator struct {
+	ID   string `xml:"id,attr"`
+	Role string `xml:"role,attr"`
+	Name string `xml:",chardata"`
+}
+
+type opfIdentifier struct {
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// opfMeta covers both the EPUB 2 <meta name content> and the EPUB 3 <meta property refines> forms
+type opfMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// parseEPUB reads the metadata of the first package document listed in the container
+func parseEPUB(archive *zip.Reader) (*EPUBMetadata, error) {
+	var container epubContainer
+	if err := readXML(archive, "META-INF/container.xml", &container); err != nil {
+		return nil, invalidInput("the file is not an EPUB: %v", err)
+	}
+	opfPath := ""
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+	if opfPath == "" {
+		return nil, invalidInput("the EPUB container lists no package document")
+	}
+
+	var pkg opfPackage
+	if err := readXML(archive, opfPath, &pkg); err != nil {
+		return nil, invalidInput("the EPUB package document could not be read: %v", err)
+	}
+	return pkg.metadata(path.Dir(opfPath)), nil
+}
+
+func readXML(archive *zip.Reader, name string, v interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := xml.NewDecoder(io.LimitReader(file, maxOPFSize))
+	// package documents are supposed to be UTF-8, accept other declared charsets as they are
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	// named HTML entities such as &nbsp; show up in hand-edited descriptions
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	return decoder.Decode(v)
+}
+
+func (pkg *opfPackage) metadata(opfDir string) *EPUBMetadata {
+	meta := &pkg.Metadata
+	result := &EPUBMetadata{Creators: []string{}, Subjects: []string{}}
+	for _, title := range meta.Titles {
+		if title = cleanText(title); title != "" {
+			result.Title = title
+			break
+		}
+	}
+
+	// EPUB 3 puts creator roles into <meta refines="#id" property="role">
+	roles := make(map[string]string)
+	for _, m := range meta.Metas {
+		if m.Property == "role" && strings.HasPrefix(m.Refines, "#") {
+			roles[strings.TrimPrefix(m.Refines, "#")] = strings.TrimSpace(m.Value)
+		}
+	}
+	for _, creator := range meta.Creators {
+		role := creator.Role
+		if role == "" && creator.ID != "" {
+			role = roles[creator.ID]
+		}
+		// illustrators, translators and editors are not the author
+		if role != "" && role != "aut" {
+			continue
+		}
+		if name := cleanText(creator.Name); name != "" {
+			result.Creators = append(result.Creators, name)
+		}
+	}
+
+	for _, id := range meta.Identifiers {
+		if isbn := epubISBN(id); isbn != "" {
+			result.ISBN = isbn
+			break
+		}
+	}
+	result.Description = htmlToText(meta.Description)
+	for _, subject := range meta.Subjects {
+		if subject = cleanText(subject); subject != "" {
+			result.Subjects = append(result.Subjects, subject)
+		}
+	}
+	if len(meta.Dates) > 0 {
+		// dates may carry a time of day, the book log only keeps the date part
+		date, _, _ := strings.Cut(cleanText(meta.Dates[0]), "T")
+		result.PublishedAt = date
+	}
+
+	if cover := pkg.coverItem(); cover != nil {
+		href, err := url.PathUnescape(cover.Href)
+		if err == nil {
+			result.CoverPath = path.Join(opfDir, href)
+		}
+	}
+	return result
+}
+
+// coverItem finds the cover image the way reading systems do: the EPUB 3 cover-image property,
+// then the EPUB 2 <meta name="cover">, then an image that calls itself a cover
+func (pkg *opfPackage) coverItem() *opfItem {
+	for i, item := range pkg.Manifest {
+		if strings.Contains(" "+item.Properties+" ", " cover-image ") {
+			return &pkg.Manifest[i]
+		}
+	}
+	for _, m := range pkg.Metadata.Metas {
+		if m.Name != "cover" || m.Content == "" {
+			continue
+		}
+		// some tools put the href instead of the item id into content
+		for i, item := range pkg.Manifest {
+			if item.ID == m.Content || item.Href == m.Content {
+				return &pkg.Manifest[i]
+			}
+		}
+	}
+	for i, item := range pkg.Manifest {
+		if strings.HasPrefix(item.MediaType, "image/") &&
+			(strings.Contains(strings.ToLower(item.ID), "cover") || strings.Contains(strings.ToLower(item.Href), "cover")) {
+			return &pkg.Manifest[i]
+		}
+	}
+	return nil
+}
+
+// epubISBN returns the ISBN-13 of an identifier, UUIDs and other identifiers give ""
+func epubISBN(id opfIdentifier) string {
+	value := strings.TrimSpace(id.Value)
+	labelled := strings.EqualFold(id.Scheme, "isbn")
+	if lower := strings.ToLower(value); strings.HasPrefix(lower, "urn:isbn:") || strings.HasPrefix(lower, "isbn:") {
+		labelled = true
+		value = value[strings.LastIndex(value, ":")+1:]
+	}
+	isbn := normalizeISBN(value)
+	if isbn == "" {
+		return ""
+	}
+	// an unlabelled identifier only counts when it is really shaped like an ISBN, X is an ISBN-10 check digit
+	if !labelled && (strings.ContainsAny(strings.ToLower(value), "abcdefghijklmnopqrstuvwyz") || !isValidISBN13(isbn)) {
+		return ""
+	}
+	return isbn
+}
+
+func isValidISBN13(isbn string) bool {
+	if len(isbn) != 13 {
+		return false
+	}
+	sum := 0
+	for i, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+var (
+	htmlParagraphPattern = regexp.MustCompile(`(?i)</p>|</div>`)
+	htmlBreakPattern     = regexp.MustCompile(`(?i)<br\s*/?>|</li>`)
+	htmlTagPattern       = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern     = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// htmlToText turns the (often escaped) XHTML of a description into plain text with paragraph breaks
+func htmlToText(s string) string {
+	s = htmlParagraphPattern.ReplaceAllString(s, "\n\n")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLinePattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// cleanText collapses whitespace and drops invalid UTF-8 from a metadata value
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+}
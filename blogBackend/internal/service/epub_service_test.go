@@ -0,0 +1,116 @@
+package service
+
+import (
+	"archive/zip"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseEPUB(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    *EPUBMetadata
+		wantErr bool
+	}{
+		{
+			name: "EPUB 2 with a meta cover",
+			file: "epub2_meta_cover.epub",
+			want: &EPUBMetadata{
+				Title:       "The Quiet Harbour",
+				Creators:    []string{"Jane Doe"},
+				ISBN:        "9780306406157",
+				Description: "A town by the sea & its keeper.\n\nSecond\nline",
+				Subjects:    []string{"Fiction", "Sea stories"},
+				PublishedAt: "2001-05-03",
+				CoverPath:   "OEBPS/images/front page.jpg",
+			},
+		},
+		{
+			name: "EPUB 3 with a cover-image and refined roles",
+			file: "epub3_cover_image.epub",
+			want: &EPUBMetadata{
+				Title:       "Night Trains",
+				Creators:    []string{"Ann Author", "Bea Writer"},
+				ISBN:        "9780306406157",
+				Subjects:    []string{},
+				PublishedAt: "2019",
+				CoverPath:   "EPUB/images/front.png",
+			},
+		},
+		{name: "missing container", file: "missing_container.epub", wantErr: true},
+		{name: "missing package document", file: "missing_opf.epub", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := zip.OpenReader(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+
+			got, err := parseEPUB(&archive.Reader)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("parseEPUB() error = %v, want invalid input", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEPUB() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEPUB() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEPUBISBN(t *testing.T) {
+	tests := []struct {
+		name string
+		id   opfIdentifier
+		want string
+	}{
+		{"urn:isbn with hyphens", opfIdentifier{Value: "urn:isbn:978-0-306-40615-7"}, "9780306406157"},
+		{"isbn prefix", opfIdentifier{Value: "ISBN:9780306406157"}, "9780306406157"},
+		{"bare ISBN-13", opfIdentifier{Value: " 9780306406157 "}, "9780306406157"},
+		{"ISBN-10 with scheme", opfIdentifier{Scheme: "ISBN", Value: "0-306-40615-2"}, "9780306406157"},
+		{"bare number with a bad check digit", opfIdentifier{Value: "9780306406158"}, ""},
+		{"bare ISBN-10", opfIdentifier{Value: "0306406152"}, "9780306406157"},
+		{"unlabelled identifier with letters", opfIdentifier{Value: "ed-0306406152"}, ""},
+		{"uuid", opfIdentifier{Value: "urn:uuid:0b1c4a52-6d2e-4c44-9a8e-3f1c2f6a7d10"}, ""},
+		{"empty", opfIdentifier{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := epubISBN(tt.id); got != tt.want {
+				t.Errorf("epubISBN(%+v) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "  A plain   description ", "A plain description"},
+		{"paragraphs", "<p>First</p>\n<p>Second</p>", "First\n\nSecond"},
+		{"breaks and lists", "<ul><li>one</li><li>two</li></ul>line<BR>next", "one\ntwo\nline\nnext"},
+		{"entities", "Fish &amp; chips&nbsp;&mdash; &lt;b&gt;", "Fish & chips — <b>"},
+		{"blank lines collapse", "<div>a</div>\n\n\n<div>b</div>", "a\n\nb"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.in); got != tt.want {
+				t.Errorf("htmlToText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidInput is wrapped by validation errors so handlers can answer with 400
+var ErrInvalidInput = errors.New("invalid input")
+
+func invalidInput(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, args...))
+}
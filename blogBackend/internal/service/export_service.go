@@ -0,0 +1,340 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"project/internal/model"
+	"project/internal/store"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported export formats
+const (
+	ExportFormatCSV      = "csv"
+	ExportFormatJSON     = "json"
+	ExportFormatMarkdown = "md"
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format, expected csv, json or md")
+
+type ExportService interface {
+	ExportBooks(userID int, format string, w io.Writer) error
+}
+
+type exportService struct {
+	bookLogStore  store.BookLogStore
+	readTimeStore store.ReadTimeStore
+}
+
+func NewExportService(bookLogStore store.BookLogStore, readTimeStore store.ReadTimeStore) ExportService {
+	return &exportService{bookLogStore: bookLogStore, readTimeStore: readTimeStore}
+}
+
+// exportBatchSize is how many book logs are loaded per query while streaming
+const exportBatchSize = 100
+
+// goodreadsHeader is the column layout of a Goodreads library export
+var goodreadsHeader = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors", "ISBN", "ISBN13",
+	"My Rating", "Average Rating", "Publisher", "Binding", "Number of Pages", "Year Published",
+	"Original Publication Year", "Date Read", "Date Added", "Bookshelves", "Bookshelves with positions",
+	"Exclusive Shelf", "My Review", "Spoiler", "Private Notes", "Read Count", "Owned Copies",
+}
+
+// csvHeader appends our own columns to the Goodreads layout, Goodreads ignores columns it doesn't know
+var csvHeader = append(append([]string{}, goodreadsHeader...), "Reading Minutes")
+
+// ExportBook is the portable representation of a book log, without user data.
+type ExportBook struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	ISBN        string     `json:"isbn"`
+	Category    string     `json:"category"`
+	Description string     `json:"description"`
+	PublishedAt string     `json:"published_at"`
+	CoverUrl    string     `json:"cover_url"`
+	Status      string     `json:"status"`
+	MyRating    *int       `json:"my_rating"`
+	MyComment   string     `json:"my_comment"`
+	PageCount   int        `json:"page_count"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	Tags        []string   `json:"tags"`
+	// ReadingMinutes sums the reading sessions logged for the book
+	ReadingMinutes int       `json:"reading_minutes"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func newExportBook(book model.BookLog, readingMinutes int) ExportBook {
+	return ExportBook{
+		ID:             book.ID,
+		Title:          book.Title,
+		Author:         book.Author,
+		ISBN:           book.ISBN,
+		Category:       book.Category,
+		Description:    book.Description,
+		PublishedAt:    book.PublishedAt,
+		CoverUrl:       book.CoverUrl,
+		Status:         book.Status,
+		MyRating:       book.MyRating,
+		MyComment:      book.MyComment,
+		PageCount:      book.PageCount,
+		FinishedAt:     book.FinishedAt,
+		Tags:           bookTagNames(book),
+		ReadingMinutes: readingMinutes,
+		CreatedAt:      book.CreatedAt,
+		UpdatedAt:      book.UpdatedAt,
+	}
+}
+
+func (s *exportService) ExportBooks(userID int, format string, w io.Writer) error {
+	switch format {
+	case ExportFormatCSV:
+		return s.exportCSV(userID, w)
+	case ExportFormatJSON:
+		return s.exportJSON(userID, w)
+	case ExportFormatMarkdown:
+		return s.exportMarkdown(userID, w)
+	default:
+		return ErrUnsupportedExportFormat
+	}
+}
+
+func (s *exportService) exportCSV(userID int, w io.Writer) error {
+	bookMinutes, err := s.readTimeStore.GetBookReadTotals(userID)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	err = s.bookLogStore.IterateBookLogs(userID, exportBatchSize, func(books []model.BookLog) error {
+		for _, book := range books {
+			isbn10, isbn13 := "", ""
+			if len(book.ISBN) == 10 {
+				isbn10 = book.ISBN
+			} else {
+				isbn13 = book.ISBN
+			}
+			rating := "0"
+			if book.MyRating != nil {
+				rating = strconv.Itoa(*book.MyRating)
+			}
+			pages, dateRead := "", ""
+			if book.PageCount > 0 {
+				pages = strconv.Itoa(book.PageCount)
+			}
+			if book.FinishedAt != nil {
+				dateRead = book.FinishedAt.Format("2006/01/02")
+			}
+			shelf := mapStatusToShelf(book.Status)
+			record := []string{
+				strconv.FormatUint(uint64(book.ID), 10), book.Title, book.Author, "", "",
+				// Goodreads wraps ISBNs like this so spreadsheets keep leading zeros
+				`="` + isbn10 + `"`, `="` + isbn13 + `"`,
+				rating, "", "", "", pages, book.PublishedAt,
+				"", dateRead, book.CreatedAt.Format("2006/01/02"), strings.Join(bookTagNames(book), ", "), "",
+				shelf, book.MyComment, "", "", "", "0",
+				strconv.Itoa(bookMinutes[book.ID]),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *exportService) exportJSON(userID int, w io.Writer) error {
+	totalMinutes, err := s.readTimeStore.GetTotalReadTime(userID)
+	if err != nil {
+		return err
+	}
+	bookMinutes, err := s.readTimeStore.GetBookReadTotals(userID)
+	if err != nil {
+		return err
+	}
+
+	// the document is written piece by piece so large libraries are never held in memory
+	header, err := json.Marshal(map[string]interface{}{
+		"exported_at":  time.Now().UTC(),
+		"reading_time": map[string]int{"total_minutes": totalMinutes},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"books":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = s.bookLogStore.IterateBookLogs(userID, exportBatchSize, func(books []model.BookLog) error {
+		for _, book := range books {
+			data, err := json.Marshal(newExportBook(book, bookMinutes[book.ID]))
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}")
+	return err
+}
+
+// exportMarkdown writes a zip archive with one note per book plus an index note.
+func (s *exportService) exportMarkdown(userID int, w io.Writer) error {
+	totalMinutes, err := s.readTimeStore.GetTotalReadTime(userID)
+	if err != nil {
+		return err
+	}
+	bookMinutes, err := s.readTimeStore.GetBookReadTotals(userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	var index strings.Builder
+	bookCount := 0
+
+	err = s.bookLogStore.IterateBookLogs(userID, exportBatchSize, func(books []model.BookLog) error {
+		for _, book := range books {
+			name := markdownFileName(book)
+			f, err := archive.Create("books/" + name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(f, markdownNote(book, bookMinutes[book.ID])); err != nil {
+				return err
+			}
+			bookCount++
+			fmt.Fprintf(&index, "- [%s](books/%s)\n", escapeMarkdownLink(book.Title), name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := archive.Create("Library.md")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "---\nexported_at: %s\nbooks: %d\nreading_minutes_total: %d\n---\n\n# Library\n\n%s",
+		time.Now().UTC().Format(time.RFC3339), bookCount, totalMinutes, index.String())
+	return archive.Close()
+}
+
+func markdownNote(book model.BookLog, readingMinutes int) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeFrontMatter(&b, "title", book.Title)
+	writeFrontMatter(&b, "author", book.Author)
+	writeFrontMatter(&b, "isbn", book.ISBN)
+	writeFrontMatter(&b, "category", book.Category)
+	writeFrontMatter(&b, "published", book.PublishedAt)
+	writeFrontMatter(&b, "status", book.Status)
+	if book.MyRating != nil {
+		fmt.Fprintf(&b, "rating: %d\n", *book.MyRating)
+	}
+	if book.PageCount > 0 {
+		fmt.Fprintf(&b, "pages: %d\n", book.PageCount)
+	}
+	if book.FinishedAt != nil {
+		fmt.Fprintf(&b, "finished: %s\n", book.FinishedAt.Format("2006-01-02"))
+	}
+	if readingMinutes > 0 {
+		fmt.Fprintf(&b, "reading_minutes: %d\n", readingMinutes)
+	}
+	writeFrontMatter(&b, "cover", book.CoverUrl)
+	if tags := bookTagNames(book); len(tags) > 0 {
+		quoted, _ := json.Marshal(tags)
+		fmt.Fprintf(&b, "tags: %s\n", quoted)
+	}
+	fmt.Fprintf(&b, "added: %s\n", book.CreatedAt.Format("2006-01-02"))
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# %s\n\n", book.Title)
+	if book.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", book.Description)
+	}
+	if book.MyComment != "" {
+		fmt.Fprintf(&b, "## My notes\n\n%s\n", book.MyComment)
+	}
+	return b.String()
+}
+
+// writeFrontMatter writes a YAML key with a JSON-quoted value, JSON strings are valid YAML.
+func writeFrontMatter(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	quoted, _ := json.Marshal(value)
+	fmt.Fprintf(b, "%s: %s\n", key, quoted)
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func markdownFileName(book model.BookLog) string {
+	slug := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.ToLower(book.Title), "-"), "-")
+	if slug == "" {
+		slug = "book"
+	}
+	return fmt.Sprintf("%s-%d.md", truncate(slug, 80), book.ID)
+}
+
+func escapeMarkdownLink(text string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+}
+
+func bookTagNames(book model.BookLog) []string {
+	names := make([]string, 0, len(book.Tags))
+	for _, tag := range book.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// mapStatusToShelf is the reverse of mapShelfToStatus.
+func mapStatusToShelf(status string) string {
+	switch status {
+	case model.BookStatusRead:
+		return "read"
+	case model.BookStatusReading:
+		return "currently-reading"
+	case model.BookStatusDidNotFinish:
+		return "did-not-finish"
+	default:
+		return "to-read"
+	}
+}
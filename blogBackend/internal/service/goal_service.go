@@ -0,0 +1,165 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"project/internal/model"
+	"project/internal/store"
+	"time"
+)
+
+type GoalInput struct {
+	TargetBooks int `json:"targetBooks"`
+	TargetPages int `json:"targetPages"`
+}
+
+// GoalProgress compares what was read in a year with the goal and with a linear pace line
+type GoalProgress struct {
+	Year        int `json:"year"`
+	TargetBooks int `json:"target_books"`
+	TargetPages int `json:"target_pages"`
+	BooksRead   int `json:"books_read"`
+	PagesRead   int `json:"pages_read"`
+
+	// share of the year that has passed, 1 for past years
+	YearElapsed   float64 `json:"year_elapsed"`
+	ExpectedBooks float64 `json:"expected_books"`
+	ExpectedPages float64 `json:"expected_pages"`
+	// positive when ahead of the pace line, negative when behind
+	BooksAhead int `json:"books_ahead"`
+	PagesAhead int `json:"pages_ahead"`
+
+	Completed bool   `json:"completed"`
+	Summary   string `json:"summary"`
+}
+
+type GoalService interface {
+	SetGoal(userID int, year int, input GoalInput) (*GoalProgress, error)
+	GetGoalProgress(userID int, year int) (*GoalProgress, error)
+	GetGoalHistory(userID int) ([]GoalProgress, error)
+	DeleteGoal(userID int, year int) error
+}
+
+type goalService struct {
+	goalStore    store.GoalStore
+	bookLogStore store.BookLogStore
+}
+
+func NewGoalService(goalStore store.GoalStore, bookLogStore store.BookLogStore) GoalService {
+	return &goalService{goalStore: goalStore, bookLogStore: bookLogStore}
+}
+
+func (s *goalService) SetGoal(userID int, year int, input GoalInput) (*GoalProgress, error) {
+	if year < 1900 || year > 9999 {
+		return nil, invalidInput("year %d is out of range", year)
+	}
+	if input.TargetBooks < 0 || input.TargetPages < 0 {
+		return nil, invalidInput("targets cannot be negative")
+	}
+	if input.TargetBooks == 0 && input.TargetPages == 0 {
+		return nil, invalidInput("set a book target, a page target or both")
+	}
+
+	goal := &model.ReadingGoal{
+		UserID:      uint(userID),
+		Year:        year,
+		TargetBooks: input.TargetBooks,
+		TargetPages: input.TargetPages,
+	}
+	if err := s.goalStore.UpsertGoal(goal); err != nil {
+		return nil, err
+	}
+	return s.progress(userID, goal, time.Now())
+}
+
+func (s *goalService) GetGoalProgress(userID int, year int) (*GoalProgress, error) {
+	goal, err := s.goalStore.GetGoalByYear(userID, year)
+	if err != nil {
+		return nil, err
+	}
+	return s.progress(userID, goal, time.Now())
+}
+
+func (s *goalService) GetGoalHistory(userID int) ([]GoalProgress, error) {
+	goals, err := s.goalStore.GetGoals(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	history := make([]GoalProgress, 0, len(goals))
+	for i := range goals {
+		progress, err := s.progress(userID, &goals[i], now)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *progress)
+	}
+	return history, nil
+}
+
+func (s *goalService) DeleteGoal(userID int, year int) error {
+	return s.goalStore.DeleteGoal(userID, year)
+}
+
+func (s *goalService) progress(userID int, goal *model.ReadingGoal, now time.Time) (*GoalProgress, error) {
+	start := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(1, 0, 0)
+
+	books, pages, err := s.bookLogStore.GetFinishedTotals(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	elapsed := 0.0
+	switch {
+	case !now.Before(end):
+		elapsed = 1
+	case now.After(start):
+		elapsed = now.Sub(start).Seconds() / end.Sub(start).Seconds()
+	}
+
+	p := &GoalProgress{
+		Year:          goal.Year,
+		TargetBooks:   goal.TargetBooks,
+		TargetPages:   goal.TargetPages,
+		BooksRead:     int(books),
+		PagesRead:     int(pages),
+		YearElapsed:   elapsed,
+		ExpectedBooks: float64(goal.TargetBooks) * elapsed,
+		ExpectedPages: float64(goal.TargetPages) * elapsed,
+	}
+	// a book only counts as behind once the pace line has fully passed it
+	p.BooksAhead = p.BooksRead - int(math.Floor(p.ExpectedBooks))
+	p.PagesAhead = p.PagesRead - int(math.Floor(p.ExpectedPages))
+	p.Completed = p.BooksRead >= goal.TargetBooks && p.PagesRead >= goal.TargetPages
+	p.Summary = goalSummary(p, elapsed == 1)
+	return p, nil
+}
+
+func goalSummary(p *GoalProgress, yearOver bool) string {
+	if p.Completed {
+		return "Goal completed"
+	}
+	if yearOver {
+		if p.TargetBooks > 0 {
+			return fmt.Sprintf("Finished %d of %d books", p.BooksRead, p.TargetBooks)
+		}
+		return fmt.Sprintf("Read %d of %d pages", p.PagesRead, p.TargetPages)
+	}
+
+	unit, ahead := "books", p.BooksAhead
+	if p.TargetBooks == 0 {
+		unit, ahead = "pages", p.PagesAhead
+	}
+	if ahead == 1 || ahead == -1 {
+		unit = unit[:len(unit)-1]
+	}
+	switch {
+	case ahead < 0:
+		return fmt.Sprintf("%d %s behind schedule", -ahead, unit)
+	case ahead > 0:
+		return fmt.Sprintf("%d %s ahead of schedule", ahead, unit)
+	default:
+		return "On track"
+	}
+}
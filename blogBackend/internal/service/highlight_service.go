@@ -0,0 +1,158 @@
+package service
+
+import (
+	"hash/fnv"
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HighlightInput struct {
+	Page     *int     `json:"page"`
+	Location string   `json:"location"`
+	Quote    string   `json:"quote"`
+	Note     string   `json:"note"`
+	Tags     []string `json:"tags"`
+}
+
+type HighlightService interface {
+	CreateHighlight(userID int, bookID int, input HighlightInput) (*model.Highlight, error)
+	GetHighlight(highlightID int, userID int) (*model.Highlight, error)
+	GetHighlightsByBookID(bookID int, userID int) ([]model.Highlight, error)
+	UpdateHighlight(highlightID int, userID int, input HighlightInput) (*model.Highlight, error)
+	DeleteHighlight(highlightID int, userID int) error
+	SearchHighlights(userID int, query string, tag string, page, pageSize int) ([]model.Highlight, int64, error)
+
+	// GetRandomHighlight returns a random highlight, or the same one for the whole day when daily is set.
+	GetRandomHighlight(userID int, daily bool) (*model.Highlight, error)
+}
+
+type highlightService struct {
+	highlightStore store.HighlightStore
+	bookLogStore   store.BookLogStore
+}
+
+func NewHighlightService(highlightStore store.HighlightStore, bookLogStore store.BookLogStore) HighlightService {
+	return &highlightService{highlightStore: highlightStore, bookLogStore: bookLogStore}
+}
+
+func (s *highlightService) CreateHighlight(userID int, bookID int, input HighlightInput) (*model.Highlight, error) {
+	if err := validateHighlightInput(input); err != nil {
+		return nil, err
+	}
+	// make sure the book belongs to the caller
+	if _, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID); err != nil {
+		return nil, err
+	}
+
+	highlight := &model.Highlight{
+		UserID:    uint(userID),
+		BookLogID: uint(bookID),
+		Page:      input.Page,
+		Location:  strings.TrimSpace(input.Location),
+		Quote:     strings.TrimSpace(input.Quote),
+		Note:      strings.TrimSpace(input.Note),
+		Tags:      normalizeHighlightTags(input.Tags),
+	}
+	if err := s.highlightStore.CreateHighlight(highlight); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+func (s *highlightService) GetHighlight(highlightID int, userID int) (*model.Highlight, error) {
+	return s.highlightStore.GetHighlightByIDAndUserID(highlightID, userID)
+}
+
+func (s *highlightService) GetHighlightsByBookID(bookID int, userID int) ([]model.Highlight, error) {
+	if _, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID); err != nil {
+		return nil, err
+	}
+	return s.highlightStore.GetHighlightsByBookID(bookID, userID)
+}
+
+func (s *highlightService) UpdateHighlight(highlightID int, userID int, input HighlightInput) (*model.Highlight, error) {
+	if err := validateHighlightInput(input); err != nil {
+		return nil, err
+	}
+	highlight, err := s.highlightStore.GetHighlightByIDAndUserID(highlightID, userID)
+	if err != nil {
+		return nil, err
+	}
+	highlight.Page = input.Page
+	highlight.Location = strings.TrimSpace(input.Location)
+	highlight.Quote = strings.TrimSpace(input.Quote)
+	highlight.Note = strings.TrimSpace(input.Note)
+	highlight.Tags = normalizeHighlightTags(input.Tags)
+
+	if err := s.highlightStore.UpdateHighlight(highlight); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+func (s *highlightService) DeleteHighlight(highlightID int, userID int) error {
+	return s.highlightStore.DeleteHighlight(highlightID, userID)
+}
+
+func (s *highlightService) SearchHighlights(userID int, query string, tag string, page, pageSize int) ([]model.Highlight, int64, error) {
+	return s.highlightStore.SearchHighlights(userID, strings.TrimSpace(query), strings.ToLower(strings.TrimSpace(tag)), page, pageSize)
+}
+
+func (s *highlightService) GetRandomHighlight(userID int, daily bool) (*model.Highlight, error) {
+	if !daily {
+		return s.highlightStore.GetHighlightAt(userID, -1)
+	}
+
+	total, err := s.highlightStore.CountHighlights(userID)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// hash the date so the daily quote is stable for a day but does not simply walk the list
+	h := fnv.New32a()
+	h.Write([]byte(time.Now().Format("2006-01-02")))
+	return s.highlightStore.GetHighlightAt(userID, int(h.Sum32()%uint32(total)))
+}
+
+func validateHighlightInput(input HighlightInput) error {
+	if strings.TrimSpace(input.Quote) == "" && strings.TrimSpace(input.Note) == "" {
+		return invalidInput("a highlight needs a quote or a note")
+	}
+	if input.Page != nil && *input.Page < 0 {
+		return invalidInput("page cannot be negative")
+	}
+	if len(input.Location) > 50 {
+		return invalidInput("location is too long")
+	}
+	return nil
+}
+
+func normalizeHighlightTags(names []string) []model.HighlightTag {
+	normalized := normalizeTagNames(names)
+	tags := make([]model.HighlightTag, 0, len(normalized))
+	for _, name := range normalized {
+		tags = append(tags, model.HighlightTag{Name: name})
+	}
+	return tags
+}
+
+// normalizeTagNames lowercases, trims and dedupes tag names
+func normalizeTagNames(names []string) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = truncate(strings.ToLower(strings.TrimSpace(name)), 50)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"project/internal/model"
+	"project/internal/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ImportService interface {
+	StartImport(userID int, source string, r io.Reader) (*model.ImportJob, error)
+	GetImportJob(jobID int, userID int) (*model.ImportJob, error)
+}
+
+type importService struct {
+	importStore  store.ImportJobStore
+	bookLogStore store.BookLogStore
+}
+
+func NewImportService(importStore store.ImportJobStore, bookLogStore store.BookLogStore) ImportService {
+	return &importService{importStore: importStore, bookLogStore: bookLogStore}
+}
+
+// importRow is a CSV row already mapped onto our book log fields.
+type importRow struct {
+	line int
+	book model.BookLog
+	err  error
+}
+
+// progress is flushed to the database every progressInterval rows
+const progressInterval = 20
+
+func (s *importService) StartImport(userID int, source string, r io.Reader) (*model.ImportJob, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %v", err)
+	}
+	if len(records) < 1 {
+		return nil, errors.New("CSV file is empty")
+	}
+
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		header[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if source == "" {
+		source = detectImportSource(header)
+	}
+
+	var mapRow func(record []string, header map[string]int) (model.BookLog, error)
+	switch source {
+	case model.ImportSourceGoodreads:
+		mapRow = mapGoodreadsRow
+	case model.ImportSourceStoryGraph:
+		mapRow = mapStoryGraphRow
+	default:
+		return nil, errors.New("unrecognized CSV format, expected a Goodreads or StoryGraph export")
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		book, err := mapRow(record, header)
+		// line numbers are 1-based and the header is line 1
+		rows = append(rows, importRow{line: i + 2, book: book, err: err})
+	}
+
+	job := &model.ImportJob{
+		UserID:    uint(userID),
+		Source:    source,
+		Status:    model.ImportStatusPending,
+		TotalRows: len(rows),
+	}
+	if err := s.importStore.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	// the import updates its own copy, job is still being encoded for the response
+	running := *job
+	go s.runImport(userID, &running, rows)
+	return job, nil
+}
+
+func (s *importService) GetImportJob(jobID int, userID int) (*model.ImportJob, error) {
+	return s.importStore.GetJobByIDAndUserID(jobID, userID)
+}
+
+func (s *importService) runImport(userID int, job *model.ImportJob, rows []importRow) {
+	job.Status = model.ImportStatusRunning
+	s.saveProgress(job)
+
+	existing, err := s.bookLogStore.FindBookLogByStatus(userID, "")
+	if err != nil {
+		job.Status = model.ImportStatusFailed
+		job.Error = err.Error()
+		s.saveProgress(job)
+		return
+	}
+
+	// dedupe against the existing library and against earlier rows of the same file
+	seen := make(map[string]bool)
+	for _, book := range existing {
+		for _, key := range bookDedupeKeys(book.ISBN, book.Title, book.Author) {
+			seen[key] = true
+		}
+	}
+
+	for _, row := range rows {
+		job.ProcessedRows++
+		if row.err != nil {
+			s.recordRowError(job, row, row.err.Error())
+		} else {
+			keys := bookDedupeKeys(row.book.ISBN, row.book.Title, row.book.Author)
+			duplicate := false
+			for _, key := range keys {
+				if seen[key] {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				job.SkippedRows++
+			} else if err := s.bookLogStore.Create(userID, &row.book); err != nil {
+				s.recordRowError(job, row, err.Error())
+			} else {
+				job.ImportedRows++
+				for _, key := range keys {
+					seen[key] = true
+				}
+			}
+		}
+
+		if job.ProcessedRows%progressInterval == 0 {
+			s.saveProgress(job)
+		}
+	}
+
+	job.Status = model.ImportStatusCompleted
+	s.saveProgress(job)
+}
+
+func (s *importService) recordRowError(job *model.ImportJob, row importRow, message string) {
+	job.FailedRows++
+	rowErr := &model.ImportRowError{
+		ImportJobID: job.ID,
+		Line:        row.line,
+		Title:       row.book.Title,
+		Message:     message,
+	}
+	if err := s.importStore.CreateRowError(rowErr); err != nil {
+		log.Printf("Import job %d - failed to record error for line %d: %v", job.ID, row.line, err)
+	}
+}
+
+func (s *importService) saveProgress(job *model.ImportJob) {
+	if err := s.importStore.UpdateJob(job); err != nil {
+		log.Printf("Import job %d - failed to save progress: %v", job.ID, err)
+	}
+}
+
+func detectImportSource(header map[string]int) string {
+	if _, ok := header["Exclusive Shelf"]; ok {
+		return model.ImportSourceGoodreads
+	}
+	if _, ok := header["Read Status"]; ok {
+		return model.ImportSourceStoryGraph
+	}
+	return ""
+}
+
+func mapGoodreadsRow(record []string, header map[string]int) (model.BookLog, error) {
+	book := model.BookLog{
+		Title:  truncate(csvField(record, header, "Title"), 100),
+		Author: truncate(csvField(record, header, "Author"), 100),
+		ISBN:   normalizeISBN(csvField(record, header, "ISBN13")),
+		Status: mapShelfToStatus(csvField(record, header, "Exclusive Shelf")),
+	}
+	if book.ISBN == "" {
+		book.ISBN = normalizeISBN(csvField(record, header, "ISBN"))
+	}
+	if book.Title == "" {
+		return book, errors.New("missing title")
+	}
+
+	// Goodreads uses 0 for "not rated"
+	if raw := csvField(record, header, "My Rating"); raw != "" {
+		rating, err := strconv.Atoi(raw)
+		if err != nil || rating < 0 || rating > 5 {
+			return book, fmt.Errorf("invalid rating %q", raw)
+		}
+		if rating > 0 {
+			book.MyRating = &rating
+		}
+	}
+
+	if raw := csvField(record, header, "Number of Pages"); raw != "" {
+		pages, err := strconv.Atoi(raw)
+		if err != nil || pages < 0 {
+			return book, fmt.Errorf("invalid number of pages %q", raw)
+		}
+		book.PageCount = pages
+	}
+	if raw := csvField(record, header, "Date Read"); raw != "" {
+		finishedAt, err := time.ParseInLocation("2006/01/02", raw, time.Local)
+		if err != nil {
+			return book, fmt.Errorf("invalid date read %q", raw)
+		}
+		book.FinishedAt = &finishedAt
+	}
+
+	review := csvField(record, header, "My Review")
+	review = strings.NewReplacer("<br/>", "\n", "<br />", "\n", "<br>", "\n").Replace(review)
+	book.MyComment = review
+	return book, nil
+}
+
+func mapStoryGraphRow(record []string, header map[string]int) (model.BookLog, error) {
+	book := model.BookLog{
+		Title:     truncate(csvField(record, header, "Title"), 100),
+		Author:    truncate(csvField(record, header, "Authors"), 100),
+		ISBN:      normalizeISBN(csvField(record, header, "ISBN/UID")),
+		Status:    mapShelfToStatus(csvField(record, header, "Read Status")),
+		MyComment: csvField(record, header, "Review"),
+	}
+	if book.Title == "" {
+		return book, errors.New("missing title")
+	}
+
+	// StoryGraph allows quarter stars, our ratings are whole numbers
+	if raw := csvField(record, header, "Star Rating"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || value > 5 {
+			return book, fmt.Errorf("invalid rating %q", raw)
+		}
+		rating := int(math.Round(value))
+		if rating > 0 {
+			book.MyRating = &rating
+		}
+	}
+
+	if raw := csvField(record, header, "Last Date Read"); raw != "" {
+		finishedAt, err := time.ParseInLocation("2006/01/02", raw, time.Local)
+		if err != nil {
+			return book, fmt.Errorf("invalid last date read %q", raw)
+		}
+		book.FinishedAt = &finishedAt
+	}
+	return book, nil
+}
+
+// mapShelfToStatus maps Goodreads exclusive shelves and StoryGraph read statuses onto our statuses.
+func mapShelfToStatus(shelf string) string {
+	switch strings.ToLower(strings.TrimSpace(shelf)) {
+	case "read":
+		return model.BookStatusRead
+	case "currently-reading", "paused":
+		return model.BookStatusReading
+	case "did-not-finish":
+		return model.BookStatusDidNotFinish
+	default:
+		return model.BookStatusWantToRead
+	}
+}
+
+func csvField(record []string, header map[string]int, name string) string {
+	i, ok := header[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// normalizeISBN strips Goodreads' ="..." wrapping, hyphens and spaces.
+// ISBN-10 values are converted to ISBN-13 so both forms compare equal.
+func normalizeISBN(raw string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(raw) {
+		if (r >= '0' && r <= '9') || r == 'X' {
+			b.WriteRune(r)
+		}
+	}
+	isbn := b.String()
+	if len(isbn) == 10 {
+		return isbn10To13(isbn)
+	}
+	if len(isbn) != 13 {
+		return ""
+	}
+	return isbn
+}
+
+func isbn10To13(isbn10 string) string {
+	digits := "978" + isbn10[:9]
+	sum := 0
+	for i, r := range digits {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
+
+// bookDedupeKeys returns the keys two book logs are considered the same book by.
+func bookDedupeKeys(isbn, title, author string) []string {
+	var keys []string
+	if normalized := normalizeISBN(isbn); normalized != "" {
+		keys = append(keys, "isbn:"+normalized)
+	}
+	if title != "" {
+		keys = append(keys, "title:"+strings.ToLower(strings.TrimSpace(title))+"|"+strings.ToLower(strings.TrimSpace(author)))
+	}
+	return keys
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"project/internal/model"
+	"project/internal/store"
+	"sort"
+	"time"
+)
+
+// LeaderboardEntry is a reader's place on a leaderboard, readers with the same score share a rank
+type LeaderboardEntry struct {
+	Rank          int              `json:"rank"`
+	User          model.PublicUser `json:"user"`
+	Minutes       int64            `json:"minutes"`
+	BooksFinished int64            `json:"books_finished"`
+	IsViewer      bool             `json:"is_viewer"`
+}
+
+// Leaderboard ranks the viewer and the readers they follow, who follow them back, for the current week or month
+type Leaderboard struct {
+	Period      string             `json:"period"`
+	Metric      string             `json:"metric"`
+	From        string             `json:"from"`
+	To          string             `json:"to"`
+	Timezone    string             `json:"timezone"`
+	Entries     []LeaderboardEntry `json:"entries"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// leaderboardTotals is a cached computation, shared by both metrics
+type leaderboardTotals struct {
+	rows        []store.LeaderboardRow
+	generatedAt time.Time
+}
+
+type LeaderboardService interface {
+	// GetLeaderboard ranks by "minutes" read or "books" finished in the current "week" or "month",
+	// counted in the viewer's timezone. Readers who opted out only show up on their own board.
+	GetLeaderboard(viewerID int, period, metric string) (*Leaderboard, error)
+}
+
+type leaderboardService struct {
+	statsStore store.StatsStore
+	userStore  store.UserStore
+	cache      *ttlCache[*leaderboardTotals]
+}
+
+func NewLeaderboardService(statsStore store.StatsStore, userStore store.UserStore, cacheTTL time.Duration) LeaderboardService {
+	return &leaderboardService{statsStore: statsStore, userStore: userStore, cache: newTTLCache[*leaderboardTotals](cacheTTL)}
+}
+
+func (s *leaderboardService) GetLeaderboard(viewerID int, period, metric string) (*Leaderboard, error) {
+	if period == "" {
+		period = "week"
+	}
+	if period != "week" && period != "month" {
+		return nil, invalidInput("period must be week or month")
+	}
+	if metric == "" {
+		metric = "minutes"
+	}
+	if metric != "minutes" && metric != "books" {
+		return nil, invalidInput("metric must be minutes or books")
+	}
+
+	viewer, err := s.userStore.FindUserByID(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadTimezone(viewer.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	start := periodStart(time.Now().In(loc), period)
+	end := addPeriods(start, period, 1)
+
+	// the period start is part of the key, so a new week or month starts from a fresh board
+	key := fmt.Sprintf("%d|%s|%s|%s", viewerID, period, loc.String(), start.Format("2006-01-02"))
+	totals, ok := s.cache.get(key)
+	if !ok {
+		rows, err := s.statsStore.GetLeaderboard(viewerID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		totals = &leaderboardTotals{rows: rows, generatedAt: time.Now()}
+		s.cache.set(key, totals)
+	}
+
+	score := func(row store.LeaderboardRow) int64 {
+		if metric == "books" {
+			return row.BooksFinished
+		}
+		return row.Minutes
+	}
+	rows := append([]store.LeaderboardRow(nil), totals.rows...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if score(rows[i]) != score(rows[j]) {
+			return score(rows[i]) > score(rows[j])
+		}
+		return rows[i].UserName < rows[j].UserName
+	})
+
+	board := &Leaderboard{
+		Period:      period,
+		Metric:      metric,
+		From:        start.Format("2006-01-02"),
+		To:          end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Timezone:    loc.String(),
+		Entries:     make([]LeaderboardEntry, 0, len(rows)),
+		GeneratedAt: totals.generatedAt,
+	}
+	for i, row := range rows {
+		rank := i + 1
+		if i > 0 && score(row) == score(rows[i-1]) {
+			rank = board.Entries[i-1].Rank
+		}
+		board.Entries = append(board.Entries, LeaderboardEntry{
+			Rank:          rank,
+			User:          model.PublicUser{ID: row.UserID, UserName: row.UserName},
+			Minutes:       row.Minutes,
+			BooksFinished: row.BooksFinished,
+			IsViewer:      row.UserID == uint(viewerID),
+		})
+	}
+	return board, nil
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// overdueBatchSize is how many overdue loans one notifier pass handles at most
+const overdueBatchSize = 200
+
+type LoanInput struct {
+	// BorrowerUserID lends to a registered user, otherwise BorrowerName is required
+	BorrowerUserID *uint  `json:"borrowerUserId"`
+	BorrowerName   string `json:"borrowerName"`
+	// dates are RFC 3339 timestamps or YYYY-MM-DD, LentAt defaults to now
+	LentAt string `json:"lentAt"`
+	DueAt  string `json:"dueAt"`
+}
+
+type ReturnLoanInput struct {
+	// ReturnedAt defaults to now
+	ReturnedAt string `json:"returnedAt"`
+}
+
+// BorrowedLoan is a loan as the borrower sees it, the lender's book comes without their personal notes
+type BorrowedLoan struct {
+	ID         uint             `json:"id"`
+	Lender     model.PublicUser `json:"lender"`
+	Book       PublicBookLog    `json:"book"`
+	LentAt     time.Time        `json:"lent_at"`
+	DueAt      *time.Time       `json:"due_at"`
+	ReturnedAt *time.Time       `json:"returned_at"`
+}
+
+type LoanService interface {
+	LendBook(bookID int, userID int, input LoanInput) (*model.Loan, error)
+
+	// UpdateLoan changes the borrower or the dates of a loan, a new due date re-arms the overdue notification.
+	UpdateLoan(loanID int, userID int, input LoanInput) (*model.Loan, error)
+	ReturnLoan(loanID int, userID int, input ReturnLoanInput) (*model.Loan, error)
+	DeleteLoan(loanID int, userID int) error
+
+	GetOutstandingLoans(userID int) ([]model.Loan, error)
+
+	// GetOverdueLoans returns outstanding loans whose due date is before today.
+	GetOverdueLoans(userID int) ([]model.Loan, error)
+	GetBorrowedLoans(userID int) ([]BorrowedLoan, error)
+	GetBookLoans(bookID int, userID int) ([]model.Loan, error)
+
+	// NotifyOverdue sends one notification per newly overdue loan to the lender and the registered borrower.
+	NotifyOverdue() error
+
+	// StartOverdueNotifier runs NotifyOverdue now and then every interval, it blocks and is meant to run in a goroutine.
+	StartOverdueNotifier(interval time.Duration)
+}
+
+type loanService struct {
+	loanStore           store.LoanStore
+	bookLogStore        store.BookLogStore
+	userStore           store.UserStore
+	notificationService NotificationService
+}
+
+func NewLoanService(loanStore store.LoanStore, bookLogStore store.BookLogStore, userStore store.UserStore, notificationService NotificationService) LoanService {
+	return &loanService{
+		loanStore:           loanStore,
+		bookLogStore:        bookLogStore,
+		userStore:           userStore,
+		notificationService: notificationService,
+	}
+}
+
+// startOfToday is the cut-off for overdue loans, a loan due today is not overdue yet
+func startOfToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// applyLoanInput validates input and copies it onto loan
+func (s *loanService) applyLoanInput(loan *model.Loan, userID int, input LoanInput) error {
+	switch {
+	case input.BorrowerUserID != nil:
+		if *input.BorrowerUserID == uint(userID) {
+			return invalidInput("you cannot lend a book to yourself")
+		}
+		borrower, err := s.userStore.FindUserByID(int(*input.BorrowerUserID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInput("borrower %d does not exist", *input.BorrowerUserID)
+		}
+		if err != nil {
+			return err
+		}
+		loan.BorrowerUserID = &borrower.ID
+		loan.BorrowerName = borrower.UserName
+	case strings.TrimSpace(input.BorrowerName) != "":
+		name := strings.TrimSpace(input.BorrowerName)
+		if utf8.RuneCountInString(name) > 100 {
+			return invalidInput("borrowerName must be at most 100 characters")
+		}
+		loan.BorrowerUserID = nil
+		loan.BorrowerName = name
+	case loan.BorrowerName == "":
+		return invalidInput("set borrowerUserId or borrowerName")
+	}
+
+	if input.LentAt != "" {
+		lentAt, err := parseDate(input.LentAt)
+		if err != nil {
+			return invalidInput("lentAt must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		loan.LentAt = lentAt
+	}
+	if input.DueAt != "" {
+		dueAt, err := parseDate(input.DueAt)
+		if err != nil {
+			return invalidInput("dueAt must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		if loan.DueAt == nil || !loan.DueAt.Equal(dueAt) {
+			loan.OverdueNotifiedAt = nil
+		}
+		loan.DueAt = &dueAt
+	}
+	if loan.DueAt != nil && loan.DueAt.Before(loan.LentAt) {
+		return invalidInput("dueAt cannot be before lentAt")
+	}
+	return nil
+}
+
+func (s *loanService) LendBook(bookID int, userID int, input LoanInput) (*model.Loan, error) {
+	book, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := s.loanStore.GetOutstandingLoan(bookID, userID)
+	if err == nil {
+		return nil, invalidInput("the book is already lent to %s", outstanding.BorrowerName)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	loan := &model.Loan{UserID: uint(userID), BookLogID: book.ID, LentAt: time.Now()}
+	if err := s.applyLoanInput(loan, userID, input); err != nil {
+		return nil, err
+	}
+	if err := s.loanStore.CreateLoan(loan); err != nil {
+		return nil, err
+	}
+	return s.loanStore.GetLoanByID(int(loan.ID), userID)
+}
+
+func (s *loanService) UpdateLoan(loanID int, userID int, input LoanInput) (*model.Loan, error) {
+	loan, err := s.loanStore.GetLoanByID(loanID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyLoanInput(loan, userID, input); err != nil {
+		return nil, err
+	}
+	if err := s.loanStore.UpdateLoan(loan); err != nil {
+		return nil, err
+	}
+	return s.loanStore.GetLoanByID(loanID, userID)
+}
+
+func (s *loanService) ReturnLoan(loanID int, userID int, input ReturnLoanInput) (*model.Loan, error) {
+	loan, err := s.loanStore.GetLoanByID(loanID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.ReturnedAt != nil {
+		return nil, invalidInput("the loan was already returned")
+	}
+	returnedAt := time.Now()
+	if input.ReturnedAt != "" {
+		if returnedAt, err = parseDate(input.ReturnedAt); err != nil {
+			return nil, invalidInput("returnedAt must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		if returnedAt.Before(loan.LentAt) {
+			return nil, invalidInput("returnedAt cannot be before lentAt")
+		}
+	}
+	if err := s.loanStore.ReturnLoan(loanID, userID, returnedAt); err != nil {
+		return nil, err
+	}
+	return s.loanStore.GetLoanByID(loanID, userID)
+}
+
+func (s *loanService) DeleteLoan(loanID int, userID int) error {
+	return s.loanStore.DeleteLoan(loanID, userID)
+}
+
+func (s *loanService) GetOutstandingLoans(userID int) ([]model.Loan, error) {
+	return s.loanStore.GetOutstandingLoans(userID)
+}
+
+func (s *loanService) GetOverdueLoans(userID int) ([]model.Loan, error) {
+	return s.loanStore.GetOverdueLoans(userID, startOfToday())
+}
+
+func (s *loanService) GetBorrowedLoans(userID int) ([]BorrowedLoan, error) {
+	loans, err := s.loanStore.GetBorrowedLoans(userID)
+	if err != nil {
+		return nil, err
+	}
+	borrowed := make([]BorrowedLoan, 0, len(loans))
+	for _, loan := range loans {
+		book := loan.BookLog
+		book.User = loan.User
+		borrowed = append(borrowed, BorrowedLoan{
+			ID:         loan.ID,
+			Lender:     loan.User.Public(),
+			Book:       newPublicBookLog(book),
+			LentAt:     loan.LentAt,
+			DueAt:      loan.DueAt,
+			ReturnedAt: loan.ReturnedAt,
+		})
+	}
+	return borrowed, nil
+}
+
+func (s *loanService) GetBookLoans(bookID int, userID int) ([]model.Loan, error) {
+	if _, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID); err != nil {
+		return nil, err
+	}
+	return s.loanStore.GetBookLoans(bookID, userID)
+}
+
+func (s *loanService) NotifyOverdue() error {
+	for {
+		loans, err := s.loanStore.FindOverdueToNotify(startOfToday(), overdueBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, loan := range loans {
+			due := loan.DueAt.Format("2006-01-02")
+			message := fmt.Sprintf("%s lent to %s was due on %s", loan.BookLog.Title, loan.BorrowerName, due)
+			if err := s.notificationService.Notify(loan.UserID, model.NotificationLoanOverdue, message, &loan.ID); err != nil {
+				return err
+			}
+			if loan.BorrowerUserID != nil {
+				message := fmt.Sprintf("%s you borrowed was due back on %s", loan.BookLog.Title, due)
+				if err := s.notificationService.Notify(*loan.BorrowerUserID, model.NotificationLoanOverdueBorrower, message, &loan.ID); err != nil {
+					return err
+				}
+			}
+			if err := s.loanStore.MarkOverdueNotified(loan.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+		if len(loans) < overdueBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *loanService) StartOverdueNotifier(interval time.Duration) {
+	for {
+		if err := s.NotifyOverdue(); err != nil {
+			log.Printf("Loans - overdue notification failed: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -1,9 +1,18 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
+	"math"
+	"net/url"
 	"project/internal/model"
 	"project/internal/store"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
 )
 
 type UpdateBookLogInput struct {
@@ -19,6 +28,44 @@ type UpdateBookLogInput struct {
 	Status      string `json:"status"`
 	MyRating    *int   `json:"myRating"`
 	MyComment   string `json:"myComment"`
+	PageCount   int    `json:"pageCount"`
+	// FinishedAt is kept when omitted, it defaults to now when the status becomes "Read"
+	FinishedAt *time.Time `json:"finishedAt"`
+	// Visibility is kept when omitted
+	Visibility string `json:"visibility"`
+}
+
+// PublicBookLog is a book log as other users see it: no personal notes and only the owner's public profile
+type PublicBookLog struct {
+	ID          uint             `json:"id"`
+	Title       string           `json:"title"`
+	Author      string           `json:"author"`
+	CoverUrl    string           `json:"cover_url"`
+	ISBN        string           `json:"isbn"`
+	Category    string           `json:"category"`
+	PublishedAt string           `json:"published_at"`
+	Status      string           `json:"status"`
+	MyRating    *int             `json:"my_rating"`
+	FinishedAt  *time.Time       `json:"finished_at"`
+	Visibility  string           `json:"visibility"`
+	User        model.PublicUser `json:"user"`
+}
+
+func newPublicBookLog(book model.BookLog) PublicBookLog {
+	return PublicBookLog{
+		ID:          book.ID,
+		Title:       book.Title,
+		Author:      book.Author,
+		CoverUrl:    book.CoverUrl,
+		ISBN:        book.ISBN,
+		Category:    book.Category,
+		PublishedAt: book.PublishedAt,
+		Status:      book.Status,
+		MyRating:    book.MyRating,
+		FinishedAt:  book.FinishedAt,
+		Visibility:  book.Visibility,
+		User:        book.User.Public(),
+	}
 }
 
 type LogService interface {
@@ -26,15 +73,63 @@ type LogService interface {
 	FindBookLogByStatus(userID int, status string) ([]model.BookLog, error)
 	GetBookByIDAndUserID(bookID int, userID int) (*model.BookLog, error)
 	UpdateLog(BookID int, userID int, params UpdateBookLogInput) (existingLog *model.BookLog, err error)
-	SearchBookByTitleOrAuthor(query string) ([]model.BookLog, error)
+	// SearchBookByTitleOrAuthor only finds book logs the viewer may see, viewerID 0 is an anonymous visitor.
+	SearchBookByTitleOrAuthor(query string, viewerID int) ([]PublicBookLog, error)
+
+	// PatchLog applies a JSON Merge Patch (RFC 7396): omitted fields stay untouched, null clears a field.
+	PatchLog(bookID int, userID int, patch map[string]json.RawMessage) (*model.BookLog, error)
+
+	FindBookLogsByTag(userID int, tag string) ([]model.BookLog, error)
+	GetTags(userID int) ([]store.TagCount, error)
+
+	// BatchUpdate validates every item first and then applies all operations in one transaction.
+	// When any item is invalid nothing is applied and applied is false.
+	BatchUpdate(userID int, ops []BatchOperationInput) (results []BatchItemResult, applied bool, err error)
+
+	// GetNextInSeries returns the book following book in its series, nil when there is none.
+	GetNextInSeries(book *model.BookLog) (*model.BookLog, error)
+
+	// GroupBySeries groups a shelf listing by series in reading order, books outside a series come last.
+	GroupBySeries(userID int, books []model.BookLog) ([]SeriesGroup, error)
+
+	GetHistory(bookID int, userID int) ([]model.BookLogRevision, error)
+
+	// RevertRevision restores the book to how it was right after a revision by undoing every later
+	// revision, the result is recorded as one new revision.
+	RevertRevision(bookID int, userID int, revisionID int) (*model.BookLog, error)
+}
+
+// maxBatchItems caps the number of book/action pairs in one batch request
+const maxBatchItems = 500
+
+type BatchOperationInput struct {
+	Action string   `json:"action" binding:"required"`
+	IDs    []uint   `json:"ids" binding:"required"`
+	Status string   `json:"status"`
+	Rating *int     `json:"rating"`
+	Tags   []string `json:"tags"`
+}
+
+type BatchItemResult struct {
+	ID     uint   `json:"id"`
+	Action string `json:"action"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SeriesGroup is one series of a shelf listing, Series is nil for books outside any series
+type SeriesGroup struct {
+	Series *model.Series   `json:"series"`
+	Books  []model.BookLog `json:"books"`
 }
 
 type logService struct {
 	bookLogStore store.BookLogStore
+	seriesStore  store.SeriesStore
 }
 
-func NewLogService(bookLogStore store.BookLogStore) LogService {
-	return &logService{bookLogStore: bookLogStore}
+func NewLogService(bookLogStore store.BookLogStore, seriesStore store.SeriesStore) LogService {
+	return &logService{bookLogStore: bookLogStore, seriesStore: seriesStore}
 }
 
 func (s *logService) CreateBookLog(userID int, book *model.BookLog) error {
@@ -42,6 +137,17 @@ func (s *logService) CreateBookLog(userID int, book *model.BookLog) error {
 		return errors.New("book cannot be nil")
 	}
 	book.UserID = uint(userID) // Set the UserID for the book log
+	if book.PageCount < 0 {
+		return invalidInput("pageCount cannot be negative")
+	}
+	// empty means the owner's default visibility
+	if book.Visibility != "" && !isValidVisibility(book.Visibility) {
+		return invalidVisibility()
+	}
+	if book.Status == model.BookStatusRead && book.FinishedAt == nil {
+		now := time.Now()
+		book.FinishedAt = &now
+	}
 	return s.bookLogStore.Create(userID, book)
 }
 
@@ -70,10 +176,28 @@ func (s *logService) UpdateLog(BookID int, userID int, params UpdateBookLogInput
 	existingLog.Author = params.Author
 	existingLog.CoverUrl = params.CoverUrl
 	existingLog.Description = params.Description
+	existingLog.PublishedAt = params.PublishedAt
 	existingLog.ISBN = params.ISBN
+	existingLog.Category = params.Category
+	existingLog.Rating = params.Rating
+	existingLog.Review = params.Review
 	existingLog.MyRating = params.MyRating
 	existingLog.MyComment = params.MyComment
 	existingLog.Status = params.Status
+	existingLog.PageCount = params.PageCount
+	if params.FinishedAt != nil {
+		existingLog.FinishedAt = params.FinishedAt
+	}
+	if params.Visibility != "" {
+		if !isValidVisibility(params.Visibility) {
+			return nil, invalidVisibility()
+		}
+		existingLog.Visibility = params.Visibility
+	}
+	if existingLog.Status == model.BookStatusRead && existingLog.FinishedAt == nil {
+		now := time.Now()
+		existingLog.FinishedAt = &now
+	}
 
 	if err := s.bookLogStore.UpdateLog(existingLog); err != nil {
 		return nil, err
@@ -81,10 +205,396 @@ func (s *logService) UpdateLog(BookID int, userID int, params UpdateBookLogInput
 	return existingLog, nil
 }
 
-func (s *logService) SearchBookByTitleOrAuthor(query string) ([]model.BookLog, error) {
-	books, err := s.bookLogStore.SearchBookByTitleOrAuthor(query)
+func (s *logService) SearchBookByTitleOrAuthor(query string, viewerID int) ([]PublicBookLog, error) {
+	books, err := s.bookLogStore.SearchBookByTitleOrAuthor(query, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]PublicBookLog, 0, len(books))
+	for _, book := range books {
+		results = append(results, newPublicBookLog(book))
+	}
+	return results, nil
+}
+
+// patchableBookFields maps the JSON field names of UpdateBookLogInput onto book_logs columns
+var patchableBookFields = map[string]string{
+	"title":       "title",
+	"author":      "author",
+	"coverUrl":    "cover_url",
+	"description": "description",
+	"publishedAt": "published_at",
+	"isbn":        "isbn",
+	"category":    "category",
+	"rating":      "rating",
+	"review":      "review",
+	"status":      "status",
+	"myRating":    "my_rating",
+	"myComment":   "my_comment",
+	"pageCount":   "page_count",
+	"finishedAt":  "finished_at",
+	"visibility":  "visibility",
+}
+
+func (s *logService) PatchLog(bookID int, userID int, patch map[string]json.RawMessage) (*model.BookLog, error) {
+	fields := make(map[string]interface{}, len(patch))
+	for name, raw := range patch {
+		column, ok := patchableBookFields[name]
+		if !ok {
+			return nil, invalidInput("unknown field %q", name)
+		}
+		value, err := parseBookPatchValue(name, raw)
+		if err != nil {
+			return nil, err
+		}
+		fields[column] = value
+	}
+
+	// marking a book as read stamps the finish date unless the patch sets one
+	if fields["status"] == model.BookStatusRead {
+		if _, ok := fields["finished_at"]; !ok {
+			existing, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if existing.FinishedAt == nil {
+				fields["finished_at"] = time.Now()
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		if err := s.bookLogStore.PatchLog(bookID, userID, fields); err != nil {
+			return nil, err
+		}
+	}
+	return s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+}
+
+// parseBookPatchValue decodes and validates one patched field, null maps to the column's empty value.
+func parseBookPatchValue(name string, raw json.RawMessage) (interface{}, error) {
+	isNull := string(raw) == "null"
+
+	switch name {
+	case "pageCount":
+		if isNull {
+			return 0, nil
+		}
+		var pages int
+		if err := json.Unmarshal(raw, &pages); err != nil || pages < 0 {
+			return nil, invalidInput("pageCount must be a non-negative integer")
+		}
+		return pages, nil
+	case "finishedAt":
+		if isNull {
+			return nil, nil
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, invalidInput("finishedAt must be a date string")
+		}
+		finishedAt, err := parseDate(value)
+		if err != nil {
+			return nil, invalidInput("finishedAt must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		return finishedAt, nil
+	case "rating", "myRating":
+		if isNull {
+			if name == "myRating" {
+				return nil, nil
+			}
+			return 0, nil
+		}
+		var rating int
+		if err := json.Unmarshal(raw, &rating); err != nil {
+			return nil, invalidInput("%s must be an integer", name)
+		}
+		if rating < 0 || rating > 5 {
+			return nil, invalidInput("%s must be between 0 and 5", name)
+		}
+		return rating, nil
+	}
+
+	var value string
+	if !isNull {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, invalidInput("%s must be a string", name)
+		}
+		value = strings.TrimSpace(value)
+	}
+
+	switch name {
+	case "title", "author":
+		if value == "" {
+			return nil, invalidInput("%s cannot be empty", name)
+		}
+		if utf8.RuneCountInString(value) > 100 {
+			return nil, invalidInput("%s must be at most 100 characters", name)
+		}
+	case "status":
+		if !isValidBookStatus(value) {
+			return nil, invalidInput("status must be one of %q, %q, %q or %q",
+				model.BookStatusWantToRead, model.BookStatusReading, model.BookStatusRead, model.BookStatusDidNotFinish)
+		}
+	case "coverUrl":
+		if value != "" && !isCoverURL(value) {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, invalidInput("coverUrl must be an http or https URL")
+			}
+		}
+		if len(value) > 255 {
+			return nil, invalidInput("coverUrl must be at most 255 characters")
+		}
+	case "isbn":
+		if value != "" {
+			normalized := normalizeISBN(value)
+			if normalized == "" {
+				return nil, invalidInput("isbn must be a valid ISBN-10 or ISBN-13")
+			}
+			value = normalized
+		}
+	case "publishedAt":
+		if utf8.RuneCountInString(value) > 20 {
+			return nil, invalidInput("publishedAt must be at most 20 characters")
+		}
+	case "category":
+		if utf8.RuneCountInString(value) > 50 {
+			return nil, invalidInput("category must be at most 50 characters")
+		}
+	case "visibility":
+		if !isValidVisibility(value) {
+			return nil, invalidVisibility()
+		}
+	}
+	return value, nil
+}
+
+// parseDate accepts a full RFC 3339 timestamp or a plain YYYY-MM-DD date
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+func isValidVisibility(visibility string) bool {
+	switch visibility {
+	case model.VisibilityPrivate, model.VisibilityFollowers, model.VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+func invalidVisibility() error {
+	return invalidInput("visibility must be one of %q, %q or %q",
+		model.VisibilityPrivate, model.VisibilityFollowers, model.VisibilityPublic)
+}
+
+func isValidBookStatus(status string) bool {
+	switch status {
+	case model.BookStatusWantToRead, model.BookStatusReading, model.BookStatusRead, model.BookStatusDidNotFinish:
+		return true
+	}
+	return false
+}
+
+func (s *logService) FindBookLogsByTag(userID int, tag string) ([]model.BookLog, error) {
+	return s.bookLogStore.FindBookLogsByTag(userID, strings.ToLower(strings.TrimSpace(tag)))
+}
+
+func (s *logService) GetTags(userID int) ([]store.TagCount, error) {
+	return s.bookLogStore.GetTags(userID)
+}
+
+func (s *logService) GetNextInSeries(book *model.BookLog) (*model.BookLog, error) {
+	if book.SeriesID == nil || book.SeriesPosition == nil {
+		return nil, nil
+	}
+	next, err := s.seriesStore.GetNextInSeries(int(book.UserID), *book.SeriesID, *book.SeriesPosition, book.WorkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return next, err
+}
+
+func (s *logService) GroupBySeries(userID int, books []model.BookLog) ([]SeriesGroup, error) {
+	var seriesIDs []uint
+	bySeries := make(map[uint][]model.BookLog)
+	var standalone []model.BookLog
+	for _, book := range books {
+		if book.SeriesID == nil {
+			standalone = append(standalone, book)
+			continue
+		}
+		if _, ok := bySeries[*book.SeriesID]; !ok {
+			seriesIDs = append(seriesIDs, *book.SeriesID)
+		}
+		bySeries[*book.SeriesID] = append(bySeries[*book.SeriesID], book)
+	}
+
+	seriesList, err := s.seriesStore.GetSeriesByIDs(userID, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(seriesList, func(i, j int) bool { return seriesList[i].Name < seriesList[j].Name })
+
+	groups := make([]SeriesGroup, 0, len(seriesList)+1)
+	for i := range seriesList {
+		members := bySeries[seriesList[i].ID]
+		sort.SliceStable(members, func(a, b int) bool {
+			return seriesPosition(members[a]) < seriesPosition(members[b])
+		})
+		groups = append(groups, SeriesGroup{Series: &seriesList[i], Books: members})
+	}
+	if len(standalone) > 0 {
+		groups = append(groups, SeriesGroup{Books: standalone})
+	}
+	return groups, nil
+}
+
+func (s *logService) GetHistory(bookID int, userID int) ([]model.BookLogRevision, error) {
+	if _, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID); err != nil {
+		return nil, err
+	}
+	return s.bookLogStore.GetRevisions(bookID, userID)
+}
+
+func (s *logService) RevertRevision(bookID int, userID int, revisionID int) (*model.BookLog, error) {
+	if _, err := s.bookLogStore.GetRevision(revisionID, bookID, userID); err != nil {
+		return nil, err
+	}
+	revisions, err := s.bookLogStore.GetRevisions(bookID, userID)
 	if err != nil {
 		return nil, err
 	}
-	return books, nil
-}
\ No newline at end of file
+
+	// unwinding the later revisions newest first leaves every field they touched at its value
+	// right after the revision, the fields they didn't touch already are
+	oldValues := make(map[string]json.RawMessage)
+	for _, later := range revisions {
+		if later.ID <= uint(revisionID) {
+			break
+		}
+		for _, change := range later.Changes {
+			oldValues[change.Field] = change.Old
+		}
+	}
+
+	var tags []string
+	if old, ok := oldValues["tags"]; ok {
+		if err := json.Unmarshal(old, &tags); err != nil {
+			return nil, err
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+		delete(oldValues, "tags")
+	}
+	// old values are JSON keyed by column, and the columns match the BookLog JSON names,
+	// so decoding them into a BookLog gives back properly typed values
+	data, err := json.Marshal(oldValues)
+	if err != nil {
+		return nil, err
+	}
+	var old model.BookLog
+	if err := json.Unmarshal(data, &old); err != nil {
+		return nil, err
+	}
+	typed := store.RevisionFields(old)
+	fields := make(map[string]interface{}, len(oldValues))
+	for field := range oldValues {
+		if value, ok := typed[field]; ok {
+			fields[field] = value
+		}
+	}
+
+	if err := s.bookLogStore.RevertBookLog(userID, bookID, uint(revisionID), fields, tags); err != nil {
+		return nil, err
+	}
+	return s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+}
+
+func seriesPosition(book model.BookLog) float64 {
+	if book.SeriesPosition == nil {
+		return math.MaxFloat64
+	}
+	return *book.SeriesPosition
+}
+
+func (s *logService) BatchUpdate(userID int, ops []BatchOperationInput) ([]BatchItemResult, bool, error) {
+	total := 0
+	var allIDs []uint
+	for _, op := range ops {
+		total += len(op.IDs)
+		allIDs = append(allIDs, op.IDs...)
+	}
+	if len(ops) == 0 || total == 0 {
+		return nil, false, invalidInput("a batch needs at least one operation with ids")
+	}
+	if total > maxBatchItems {
+		return nil, false, invalidInput("a batch can touch at most %d items", maxBatchItems)
+	}
+
+	ownedIDs, err := s.bookLogStore.FindOwnedBookIDs(userID, allIDs)
+	if err != nil {
+		return nil, false, err
+	}
+	owned := make(map[uint]bool, len(ownedIDs))
+	for _, id := range ownedIDs {
+		owned[id] = true
+	}
+
+	valid := true
+	results := make([]BatchItemResult, 0, total)
+	storeOps := make([]store.BookLogBatchOp, 0, len(ops))
+	for _, op := range ops {
+		storeOp, opErr := validateBatchOperation(op)
+		for _, id := range op.IDs {
+			result := BatchItemResult{ID: id, Action: op.Action, OK: true}
+			switch {
+			case opErr != nil:
+				result.OK, result.Error = false, opErr.Error()
+			case !owned[id]:
+				result.OK, result.Error = false, "book not found"
+			}
+			valid = valid && result.OK
+			results = append(results, result)
+		}
+		storeOps = append(storeOps, storeOp)
+	}
+	if !valid {
+		return results, false, nil
+	}
+
+	if err := s.bookLogStore.ApplyBatch(userID, storeOps); err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+func validateBatchOperation(op BatchOperationInput) (store.BookLogBatchOp, error) {
+	storeOp := store.BookLogBatchOp{Action: op.Action, IDs: op.IDs}
+	switch op.Action {
+	case store.BatchSetStatus:
+		if !isValidBookStatus(op.Status) {
+			return storeOp, errors.New("invalid status")
+		}
+		storeOp.Status = op.Status
+	case store.BatchSetRating:
+		// a null rating clears it
+		if op.Rating != nil && (*op.Rating < 0 || *op.Rating > 5) {
+			return storeOp, errors.New("rating must be between 0 and 5")
+		}
+		storeOp.Rating = op.Rating
+	case store.BatchAddTags, store.BatchRemoveTags:
+		storeOp.Tags = normalizeTagNames(op.Tags)
+		if len(storeOp.Tags) == 0 {
+			return storeOp, errors.New("tags cannot be empty")
+		}
+	case store.BatchDelete:
+	default:
+		return storeOp, errors.New("unknown action")
+	}
+	return storeOp, nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"math"
+	"project/internal/model"
+	"project/internal/store"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MinutesGoalHit        = "hit"
+	MinutesGoalMissed     = "missed"
+	MinutesGoalInProgress = "in_progress"
+
+	// maxGoalHistoryDays caps the range of a hit/miss history
+	maxGoalHistoryDays = 366
+	// defaultGoalHistoryDays is the history shown without a range
+	defaultGoalHistoryDays = 30
+)
+
+// MinutesGoalInput sets a goal from EffectiveFrom (YYYY-MM-DD, default today) on
+type MinutesGoalInput struct {
+	Period        string `json:"period" binding:"required"`
+	TargetMinutes int    `json:"targetMinutes"`
+	EffectiveFrom string `json:"effectiveFrom"`
+}
+
+// MinutesGoalPeriod is a day or week judged by the goal in effect, Start and End are YYYY-MM-DD and inclusive
+type MinutesGoalPeriod struct {
+	Period        string `json:"period"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	TargetMinutes int    `json:"target_minutes"`
+	Minutes       int    `json:"minutes"`
+	Status        string `json:"status"`
+}
+
+// MinutesGoalToday is the progress of the current day or week, Goal and Progress are nil without a goal
+type MinutesGoalToday struct {
+	Date             string             `json:"date"`
+	Timezone         string             `json:"timezone"`
+	MinutesToday     int                `json:"minutes_today"`
+	Goal             *model.MinutesGoal `json:"goal"`
+	Progress         *MinutesGoalPeriod `json:"progress"`
+	RemainingMinutes int                `json:"remaining_minutes"`
+	Percent          float64            `json:"percent"`
+}
+
+// MinutesGoalHistory lists the days and weeks of a range that had a goal
+type MinutesGoalHistory struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Timezone string              `json:"timezone"`
+	Periods  []MinutesGoalPeriod `json:"periods"`
+	Hits     int                 `json:"hits"`
+	Misses   int                 `json:"misses"`
+}
+
+type MinutesGoalService interface {
+	// SetMinutesGoal starts a goal on a day, the goals are returned in order of their effective date.
+	// Past days keep the goal they were judged by, only a first goal may start before today.
+	SetMinutesGoal(userID int, input MinutesGoalInput) ([]model.MinutesGoal, error)
+	GetMinutesGoals(userID int) ([]model.MinutesGoal, error)
+
+	// DeleteMinutesGoal removes a goal that has not taken effect yet, a target of 0 minutes ends a goal.
+	DeleteMinutesGoal(goalID int, userID int) error
+
+	// GetTodayProgress reports the minutes read today against the goal in effect today.
+	GetTodayProgress(userID int) (*MinutesGoalToday, error)
+
+	// GetHistory judges every day or week in [from, to] (YYYY-MM-DD, default the last 30 days, up to today) by
+	// the goal in effect then. A weekly goal judges a whole week from Monday, by the goal in effect
+	// on its first day under a weekly goal.
+	GetHistory(userID int, from, to string) (*MinutesGoalHistory, error)
+}
+
+type minutesGoalService struct {
+	minutesGoalStore store.MinutesGoalStore
+	readTimeStore    store.ReadTimeStore
+	userStore        store.UserStore
+}
+
+func NewMinutesGoalService(minutesGoalStore store.MinutesGoalStore, readTimeStore store.ReadTimeStore, userStore store.UserStore) MinutesGoalService {
+	return &minutesGoalService{minutesGoalStore: minutesGoalStore, readTimeStore: readTimeStore, userStore: userStore}
+}
+
+// userToday is the user's timezone and the current day there as a date in UTC
+func (s *minutesGoalService) userToday(userID int) (*time.Location, time.Time, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	loc, err := loadTimezone(user.Timezone)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	year, month, day := time.Now().In(loc).Date()
+	return loc, time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseDay parses a YYYY-MM-DD day as a date in UTC
+func parseDay(value string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, invalidInput("%q is not a YYYY-MM-DD date", value)
+	}
+	return day, nil
+}
+
+func (s *minutesGoalService) SetMinutesGoal(userID int, input MinutesGoalInput) ([]model.MinutesGoal, error) {
+	maxTarget := maxSessionMinutes
+	switch input.Period {
+	case model.MinutesGoalDaily:
+	case model.MinutesGoalWeekly:
+		maxTarget = 7 * maxSessionMinutes
+	default:
+		return nil, invalidInput("period must be daily or weekly")
+	}
+	if input.TargetMinutes < 0 || input.TargetMinutes > maxTarget {
+		return nil, invalidInput("a %s target must be between 0 and %d minutes", input.Period, maxTarget)
+	}
+
+	_, today, err := s.userToday(userID)
+	if err != nil {
+		return nil, err
+	}
+	effectiveFrom := today
+	if input.EffectiveFrom != "" {
+		if effectiveFrom, err = parseDay(input.EffectiveFrom); err != nil {
+			return nil, err
+		}
+	}
+	if effectiveFrom.Before(today) {
+		goals, err := s.minutesGoalStore.GetMinutesGoals(userID)
+		if err != nil {
+			return nil, err
+		}
+		// a goal that already judged past days is not rewritten, a first goal may still cover what was read before it
+		if len(goals) > 0 && goals[0].EffectiveFrom < today.Format("2006-01-02") {
+			return nil, invalidInput("effectiveFrom must not be before today")
+		}
+	}
+
+	goal := &model.MinutesGoal{
+		UserID:        uint(userID),
+		Period:        input.Period,
+		TargetMinutes: input.TargetMinutes,
+		EffectiveFrom: effectiveFrom.Format("2006-01-02"),
+	}
+	if err := s.minutesGoalStore.UpsertMinutesGoal(goal); err != nil {
+		return nil, err
+	}
+	return s.minutesGoalStore.GetMinutesGoals(userID)
+}
+
+func (s *minutesGoalService) GetMinutesGoals(userID int) ([]model.MinutesGoal, error) {
+	return s.minutesGoalStore.GetMinutesGoals(userID)
+}
+
+func (s *minutesGoalService) DeleteMinutesGoal(goalID int, userID int) error {
+	_, today, err := s.userToday(userID)
+	if err != nil {
+		return err
+	}
+	goals, err := s.minutesGoalStore.GetMinutesGoals(userID)
+	if err != nil {
+		return err
+	}
+	for _, goal := range goals {
+		if goal.ID != uint(goalID) {
+			continue
+		}
+		// past days were judged by it, ending it is setting a target of 0 from today
+		if goal.EffectiveFrom <= today.Format("2006-01-02") {
+			return invalidInput("the goal has taken effect, set a target of 0 minutes to end it")
+		}
+		return s.minutesGoalStore.DeleteMinutesGoal(goalID, userID)
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// goalOn is the goal in effect on day, goals are sorted by effective date
+func goalOn(goals []model.MinutesGoal, day string) *model.MinutesGoal {
+	var goal *model.MinutesGoal
+	for i := range goals {
+		if goals[i].EffectiveFrom > day {
+			break
+		}
+		goal = &goals[i]
+	}
+	if goal == nil || goal.TargetMinutes == 0 {
+		return nil
+	}
+	return goal
+}
+
+// judge builds the goal periods of the days from first to last, minutes is keyed by day and has
+// to cover the whole weeks around them
+func judge(goals []model.MinutesGoal, minutes map[string]int, first, last, today time.Time) []MinutesGoalPeriod {
+	periods := []MinutesGoalPeriod{}
+	judgedWeeks := make(map[string]bool)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		goal := goalOn(goals, day.Format("2006-01-02"))
+		if goal == nil {
+			continue
+		}
+		start, end := day, day
+		if goal.Period == model.MinutesGoalWeekly {
+			start = periodStart(day, "week")
+			if judgedWeeks[start.Format("2006-01-02")] {
+				continue
+			}
+			judgedWeeks[start.Format("2006-01-02")] = true
+			end = start.AddDate(0, 0, 6)
+		}
+
+		period := MinutesGoalPeriod{
+			Period:        goal.Period,
+			Start:         start.Format("2006-01-02"),
+			End:           end.Format("2006-01-02"),
+			TargetMinutes: goal.TargetMinutes,
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			period.Minutes += minutes[d.Format("2006-01-02")]
+		}
+		switch {
+		case period.Minutes >= period.TargetMinutes:
+			period.Status = MinutesGoalHit
+		case !end.Before(today):
+			period.Status = MinutesGoalInProgress
+		default:
+			period.Status = MinutesGoalMissed
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// dailyMinutes sums the minutes read per day over the weeks containing first to last
+func (s *minutesGoalService) dailyMinutes(userID int, loc *time.Location, first, last time.Time) (map[string]int, error) {
+	from := periodStart(first, "week")
+	to := periodStart(last, "week").AddDate(0, 0, 7)
+	buckets, err := s.readTimeStore.GetReadTimeBuckets(userID, "day", loc,
+		time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc),
+		time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc))
+	if err != nil {
+		return nil, err
+	}
+	minutes := make(map[string]int, len(buckets))
+	for _, bucket := range buckets {
+		minutes[bucket.Start] = bucket.Minutes
+	}
+	return minutes, nil
+}
+
+func (s *minutesGoalService) GetTodayProgress(userID int) (*MinutesGoalToday, error) {
+	loc, today, err := s.userToday(userID)
+	if err != nil {
+		return nil, err
+	}
+	goals, err := s.minutesGoalStore.GetMinutesGoals(userID)
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := s.dailyMinutes(userID, loc, today, today)
+	if err != nil {
+		return nil, err
+	}
+
+	date := today.Format("2006-01-02")
+	progress := &MinutesGoalToday{Date: date, Timezone: loc.String(), MinutesToday: minutes[date]}
+	progress.Goal = goalOn(goals, date)
+	if periods := judge(goals, minutes, today, today, today); len(periods) > 0 {
+		period := periods[0]
+		progress.Progress = &period
+		if period.Minutes < period.TargetMinutes {
+			progress.RemainingMinutes = period.TargetMinutes - period.Minutes
+		}
+		progress.Percent = math.Round(float64(period.Minutes)*1000/float64(period.TargetMinutes)) / 10
+	}
+	return progress, nil
+}
+
+func (s *minutesGoalService) GetHistory(userID int, from, to string) (*MinutesGoalHistory, error) {
+	loc, today, err := s.userToday(userID)
+	if err != nil {
+		return nil, err
+	}
+	last := today
+	if to != "" {
+		if last, err = parseDay(to); err != nil {
+			return nil, err
+		}
+	}
+	first := last.AddDate(0, 0, 1-defaultGoalHistoryDays)
+	if from != "" {
+		if first, err = parseDay(from); err != nil {
+			return nil, err
+		}
+	}
+	// days still to come have nothing to judge yet
+	if last.After(today) {
+		last = today
+	}
+	if last.Before(first) {
+		return nil, invalidInput("from must not be after to or today")
+	}
+	if last.Sub(first) >= maxGoalHistoryDays*24*time.Hour {
+		return nil, invalidInput("the history covers at most %d days", maxGoalHistoryDays)
+	}
+
+	goals, err := s.minutesGoalStore.GetMinutesGoals(userID)
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := s.dailyMinutes(userID, loc, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &MinutesGoalHistory{
+		From:     first.Format("2006-01-02"),
+		To:       last.Format("2006-01-02"),
+		Timezone: loc.String(),
+		Periods:  judge(goals, minutes, first, last, today),
+	}
+	for _, period := range history.Periods {
+		switch period.Status {
+		case MinutesGoalHit:
+			history.Hits++
+		case MinutesGoalMissed:
+			history.Misses++
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"project/internal/model"
+	"project/internal/store"
+)
+
+type NotificationService interface {
+	// Notify stores a notification for a user, entityID is optional.
+	Notify(userID uint, notificationType string, message string, entityID *uint) error
+
+	GetNotifications(userID int, unreadOnly bool, page, pageSize int) (notifications []model.Notification, total int64, unread int64, err error)
+	MarkRead(notificationID int, userID int) error
+	MarkAllRead(userID int) error
+}
+
+type notificationService struct {
+	notificationStore store.NotificationStore
+}
+
+func NewNotificationService(notificationStore store.NotificationStore) NotificationService {
+	return &notificationService{notificationStore: notificationStore}
+}
+
+func (s *notificationService) Notify(userID uint, notificationType string, message string, entityID *uint) error {
+	return s.notificationStore.CreateNotification(&model.Notification{
+		UserID:   userID,
+		Type:     notificationType,
+		Message:  message,
+		EntityID: entityID,
+	})
+}
+
+func (s *notificationService) GetNotifications(userID int, unreadOnly bool, page, pageSize int) ([]model.Notification, int64, int64, error) {
+	notifications, total, err := s.notificationStore.GetNotifications(userID, unreadOnly, page, pageSize)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	unread, err := s.notificationStore.CountUnread(userID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return notifications, total, unread, nil
+}
+
+func (s *notificationService) MarkRead(notificationID int, userID int) error {
+	return s.notificationStore.MarkRead(notificationID, userID)
+}
+
+func (s *notificationService) MarkAllRead(userID int) error {
+	return s.notificationStore.MarkAllRead(userID)
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"project/internal/model"
+	"project/internal/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of OPDS 1.2 feeds and the OpenSearch description
+const (
+	OPDSNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	OPDSAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType      = "application/opensearchdescription+xml"
+)
+
+// OPDSPrefix is where the catalog is served, see OPDSHandler
+const OPDSPrefix = "/api/v1/opds"
+
+// opdsPageSize is the number of books per acquisition feed page
+const opdsPageSize = 50
+
+// OPDSFeed is an Atom feed, either a navigation feed of OPDSEntry links or an acquisition feed of books
+type OPDSFeed struct {
+	XMLName         xml.Name    `xml:"feed"`
+	Xmlns           string      `xml:"xmlns,attr"`
+	XmlnsDC         string      `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string      `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string      `xml:"xmlns:opensearch,attr"`
+	ID              string      `xml:"id"`
+	Title           string      `xml:"title"`
+	Updated         string      `xml:"updated"`
+	Author          OPDSAuthor  `xml:"author"`
+	Links           []OPDSLink  `xml:"link"`
+	TotalResults    *int64      `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage    *int        `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex      *int        `xml:"opensearch:startIndex,omitempty"`
+	Entries         []OPDSEntry `xml:"entry"`
+	// Kind is the media type the feed is served with
+	Kind string `xml:"-"`
+}
+
+type OPDSAuthor struct {
+	Name string `xml:"name"`
+}
+
+type OPDSLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type OPDSCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type OPDSText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type OPDSEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Authors    []OPDSAuthor   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Categories []OPDSCategory `xml:"category"`
+	Summary    *OPDSText      `xml:"summary,omitempty"`
+	Content    *OPDSText      `xml:"content,omitempty"`
+	Links      []OPDSLink     `xml:"link"`
+}
+
+// OpenSearchDescription tells reader apps how to search the catalog
+type OpenSearchDescription struct {
+	XMLName        xml.Name `xml:"OpenSearchDescription"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	ShortName      string   `xml:"ShortName"`
+	Description    string   `xml:"Description"`
+	InputEncoding  string   `xml:"InputEncoding"`
+	OutputEncoding string   `xml:"OutputEncoding"`
+	URL            struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Url"`
+}
+
+// OPDSBooksQuery selects the books of an acquisition feed, empty fields don't filter
+type OPDSBooksQuery struct {
+	Status string
+	Shelf  string
+	Author string
+	Search string
+	Page   int
+}
+
+type OPDSService interface {
+	// GetRoot is the start feed linking to the other navigation feeds.
+	GetRoot(userID int) (*OPDSFeed, error)
+	GetStatuses(userID int) (*OPDSFeed, error)
+	GetShelves(userID int) (*OPDSFeed, error)
+	GetAuthors(userID int) (*OPDSFeed, error)
+
+	// GetBooks is an acquisition feed of the books matching query, one page at a time.
+	GetBooks(userID int, query OPDSBooksQuery) (*OPDSFeed, error)
+	GetOpenSearch() *OpenSearchDescription
+}
+
+type opdsService struct {
+	catalogStore store.CatalogStore
+	bookLogStore store.BookLogStore
+	userStore    store.UserStore
+}
+
+func NewOPDSService(catalogStore store.CatalogStore, bookLogStore store.BookLogStore, userStore store.UserStore) OPDSService {
+	return &opdsService{catalogStore: catalogStore, bookLogStore: bookLogStore, userStore: userStore}
+}
+
+func opdsURL(path string, params url.Values) string {
+	if len(params) == 0 {
+		return OPDSPrefix + path
+	}
+	return OPDSPrefix + path + "?" + params.Encode()
+}
+
+func opdsTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// newFeed starts a feed with the links every page of the catalog carries
+func (s *opdsService) newFeed(userID int, id, title, self, kind string) (*OPDSFeed, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &OPDSFeed{
+		Xmlns:           "http://www.w3.org/2005/Atom",
+		XmlnsDC:         "http://purl.org/dc/terms/",
+		XmlnsOPDS:       "http://opds-spec.org/2010/catalog",
+		XmlnsOpenSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		ID:              fmt.Sprintf("urn:booklog:opds:%d:%s", userID, id),
+		Title:           title,
+		Updated:         opdsTime(time.Now()),
+		Author:          OPDSAuthor{Name: user.UserName},
+		Links: []OPDSLink{
+			{Rel: "self", Href: self, Type: kind},
+			{Rel: "start", Href: opdsURL("", nil), Type: OPDSNavigationType, Title: "Library"},
+			{Rel: "up", Href: opdsURL("", nil), Type: OPDSNavigationType, Title: "Library"},
+			{Rel: "search", Href: opdsURL("/opensearch.xml", nil), Type: OpenSearchType, Title: "Search"},
+		},
+		Entries: []OPDSEntry{},
+		Kind:    kind,
+	}, nil
+}
+
+// navigationEntry links to an acquisition feed or another navigation feed
+func navigationEntry(id, title, content, href, kind string) OPDSEntry {
+	return OPDSEntry{
+		Title:   title,
+		ID:      id,
+		Updated: opdsTime(time.Now()),
+		Content: &OPDSText{Type: "text", Value: content},
+		Links:   []OPDSLink{{Rel: "subsection", Href: href, Type: kind}},
+	}
+}
+
+func bookCount(count int64) string {
+	if count == 1 {
+		return "1 book"
+	}
+	return strconv.FormatInt(count, 10) + " books"
+}
+
+func (s *opdsService) GetRoot(userID int) (*OPDSFeed, error) {
+	feed, err := s.newFeed(userID, "root", "Library", opdsURL("", nil), OPDSNavigationType)
+	if err != nil {
+		return nil, err
+	}
+	feed.Entries = append(feed.Entries,
+		navigationEntry(feed.ID+":books", "All books", "Every book in the library", opdsURL("/books", nil), OPDSAcquisitionType),
+		navigationEntry(feed.ID+":status", "By status", "Want to read, reading, read and did not finish", opdsURL("/status", nil), OPDSNavigationType),
+		navigationEntry(feed.ID+":shelves", "Shelves", "Books by shelf", opdsURL("/shelves", nil), OPDSNavigationType),
+		navigationEntry(feed.ID+":authors", "Authors", "Books by author", opdsURL("/authors", nil), OPDSNavigationType),
+	)
+	return feed, nil
+}
+
+func (s *opdsService) GetStatuses(userID int) (*OPDSFeed, error) {
+	feed, err := s.newFeed(userID, "status", "By status", opdsURL("/status", nil), OPDSNavigationType)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.catalogStore.GetStatusCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		href := opdsURL("/books", url.Values{"status": {count.Key}})
+		feed.Entries = append(feed.Entries, navigationEntry(feed.ID+":"+url.QueryEscape(count.Key), count.Key, bookCount(count.Count), href, OPDSAcquisitionType))
+	}
+	return feed, nil
+}
+
+func (s *opdsService) GetShelves(userID int) (*OPDSFeed, error) {
+	feed, err := s.newFeed(userID, "shelves", "Shelves", opdsURL("/shelves", nil), OPDSNavigationType)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.bookLogStore.GetTags(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		href := opdsURL("/books", url.Values{"shelf": {tag.Name}})
+		feed.Entries = append(feed.Entries, navigationEntry(feed.ID+":"+url.QueryEscape(tag.Name), tag.Name, bookCount(tag.Count), href, OPDSAcquisitionType))
+	}
+	return feed, nil
+}
+
+func (s *opdsService) GetAuthors(userID int) (*OPDSFeed, error) {
+	feed, err := s.newFeed(userID, "authors", "Authors", opdsURL("/authors", nil), OPDSNavigationType)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.catalogStore.GetAuthorCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		href := opdsURL("/books", url.Values{"author": {count.Key}})
+		feed.Entries = append(feed.Entries, navigationEntry(feed.ID+":"+url.QueryEscape(count.Key), count.Key, bookCount(count.Count), href, OPDSAcquisitionType))
+	}
+	return feed, nil
+}
+
+func (s *opdsService) GetBooks(userID int, query OPDSBooksQuery) (*OPDSFeed, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	params := url.Values{}
+	title := "All books"
+	switch {
+	case query.Search != "":
+		params.Set("q", query.Search)
+		title = fmt.Sprintf("Search: %s", query.Search)
+	case query.Status != "":
+		params.Set("status", query.Status)
+		title = query.Status
+	case query.Shelf != "":
+		params.Set("shelf", query.Shelf)
+		title = query.Shelf
+	case query.Author != "":
+		params.Set("author", query.Author)
+		title = query.Author
+	}
+	pageURL := func(page int) string {
+		values := url.Values{}
+		for key, value := range params {
+			values[key] = value
+		}
+		if page > 1 {
+			values.Set("page", strconv.Itoa(page))
+		}
+		return opdsURL("/books", values)
+	}
+
+	feed, err := s.newFeed(userID, "books:"+params.Encode(), title, pageURL(query.Page), OPDSAcquisitionType)
+	if err != nil {
+		return nil, err
+	}
+	books, total, err := s.catalogStore.FindCatalogBooks(userID, store.CatalogFilter{
+		Status: query.Status,
+		Tag:    query.Shelf,
+		Author: query.Author,
+		Query:  strings.TrimSpace(query.Search),
+	}, query.Page, opdsPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize, startIndex := opdsPageSize, (query.Page-1)*opdsPageSize+1
+	feed.TotalResults, feed.ItemsPerPage, feed.StartIndex = &total, &pageSize, &startIndex
+	lastPage := int((total + opdsPageSize - 1) / opdsPageSize)
+	if lastPage > 1 {
+		feed.Links = append(feed.Links,
+			OPDSLink{Rel: "first", Href: pageURL(1), Type: OPDSAcquisitionType},
+			OPDSLink{Rel: "last", Href: pageURL(lastPage), Type: OPDSAcquisitionType})
+	}
+	if query.Page > 1 {
+		feed.Links = append(feed.Links, OPDSLink{Rel: "previous", Href: pageURL(query.Page - 1), Type: OPDSAcquisitionType})
+	}
+	if query.Page < lastPage {
+		feed.Links = append(feed.Links, OPDSLink{Rel: "next", Href: pageURL(query.Page + 1), Type: OPDSAcquisitionType})
+	}
+
+	var updated time.Time
+	for _, book := range books {
+		feed.Entries = append(feed.Entries, bookEntry(book))
+		if book.UpdatedAt.After(updated) {
+			updated = book.UpdatedAt
+		}
+	}
+	if !updated.IsZero() {
+		feed.Updated = opdsTime(updated)
+	}
+	return feed, nil
+}
+
+// bookEntry describes a book log. Book files are not hosted, so entries carry metadata and covers only.
+func bookEntry(book model.BookLog) OPDSEntry {
+	entry := OPDSEntry{
+		Title:      book.Title,
+		ID:         fmt.Sprintf("urn:booklog:book:%d", book.ID),
+		Updated:    opdsTime(book.UpdatedAt),
+		Issued:     book.PublishedAt,
+		Categories: []OPDSCategory{},
+		Links:      []OPDSLink{},
+	}
+	if book.Author != "" {
+		entry.Authors = []OPDSAuthor{{Name: book.Author}}
+	}
+	if book.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + book.ISBN
+	}
+	if book.Category != "" {
+		entry.Categories = append(entry.Categories, OPDSCategory{Term: book.Category, Label: book.Category})
+	}
+	for _, tag := range book.Tags {
+		entry.Categories = append(entry.Categories, OPDSCategory{Term: tag.Name, Label: tag.Name})
+	}
+	if book.Description != "" {
+		entry.Summary = &OPDSText{Type: "text", Value: book.Description}
+	}
+
+	if book.CoverUrl != "" {
+		image, thumbnail, imageType := book.CoverUrl, book.CoverUrl, ""
+		// covers uploaded here come in several sizes
+		if isCoverURL(book.CoverUrl) {
+			base := book.CoverUrl[:strings.LastIndex(book.CoverUrl, "/")+1]
+			image, thumbnail, imageType = base+"large", base+"small", "image/jpeg"
+		}
+		entry.Links = append(entry.Links,
+			OPDSLink{Rel: "http://opds-spec.org/image", Href: image, Type: imageType},
+			OPDSLink{Rel: "http://opds-spec.org/image/thumbnail", Href: thumbnail, Type: imageType})
+	}
+	return entry
+}
+
+func (s *opdsService) GetOpenSearch() *OpenSearchDescription {
+	description := &OpenSearchDescription{
+		Xmlns:          "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:      "Library",
+		Description:    "Search the books of your library by title, author or ISBN",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+	}
+	description.URL.Type = OPDSAcquisitionType
+	description.URL.Template = opdsURL("/books", nil) + "?q={searchTerms}"
+	return description
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// sortKeyDigits are the digits of a sort key, in byte order
+	sortKeyDigits = "abcdefghijklmnopqrstuvwxyz"
+	// maxSortKeyLength is when keys grown by repeated moves into the same gap get respaced
+	maxSortKeyLength = 32
+)
+
+type QueueInput struct {
+	BookID uint `json:"bookId" binding:"required"`
+	// Position is zero-based and defaults to the end of the queue
+	Position *int `json:"position"`
+}
+
+type MoveQueueInput struct {
+	Position *int `json:"position" binding:"required"`
+}
+
+type QueueService interface {
+	GetQueue(userID int) ([]model.QueueEntry, error)
+
+	// AddBook queues a want-to-read book and returns the new queue.
+	AddBook(userID int, input QueueInput) ([]model.QueueEntry, error)
+
+	// MoveBook moves a queued book to a zero-based position and returns the new queue.
+	MoveBook(userID int, bookID int, position int) ([]model.QueueEntry, error)
+	PinBook(userID int, bookID int) ([]model.QueueEntry, error)
+	RemoveBook(userID int, bookID int) error
+
+	// PopNext takes the first book off the queue and marks it as reading.
+	PopNext(userID int) (*model.BookLog, error)
+}
+
+type queueService struct {
+	queueStore   store.QueueStore
+	bookLogStore store.BookLogStore
+}
+
+func NewQueueService(queueStore store.QueueStore, bookLogStore store.BookLogStore) QueueService {
+	return &queueService{queueStore: queueStore, bookLogStore: bookLogStore}
+}
+
+func (s *queueService) GetQueue(userID int) ([]model.QueueEntry, error) {
+	return s.queueStore.GetQueue(userID)
+}
+
+func (s *queueService) AddBook(userID int, input QueueInput) ([]model.QueueEntry, error) {
+	book, err := s.bookLogStore.GetBookByIDAndUserID(int(input.BookID), userID)
+	if err != nil {
+		return nil, err
+	}
+	if book.Status != model.BookStatusWantToRead {
+		return nil, invalidInput("only books with status %q can be queued", model.BookStatusWantToRead)
+	}
+	queue, err := s.queueStore.GetQueue(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range queue {
+		if entry.BookLogID == book.ID {
+			return nil, invalidInput("the book is already in the queue, move it instead")
+		}
+	}
+	position := len(queue)
+	if input.Position != nil {
+		position = *input.Position
+	}
+	if err := s.place(userID, book.ID, queue, position, true); err != nil {
+		return nil, err
+	}
+	return s.queueStore.GetQueue(userID)
+}
+
+func (s *queueService) MoveBook(userID int, bookID int, position int) ([]model.QueueEntry, error) {
+	queue, err := s.queueStore.GetQueue(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.place(userID, uint(bookID), queue, position, false); err != nil {
+		return nil, err
+	}
+	return s.queueStore.GetQueue(userID)
+}
+
+func (s *queueService) PinBook(userID int, bookID int) ([]model.QueueEntry, error) {
+	return s.MoveBook(userID, bookID, 0)
+}
+
+func (s *queueService) RemoveBook(userID int, bookID int) error {
+	return s.queueStore.RemoveEntry(userID, bookID)
+}
+
+func (s *queueService) PopNext(userID int) (*model.BookLog, error) {
+	queue, err := s.queueStore.GetQueue(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(queue) == 0 {
+		return nil, invalidInput("the queue is empty")
+	}
+	next := queue[0].BookLogID
+	if err := s.bookLogStore.StartQueuedBook(userID, next); err != nil {
+		return nil, err
+	}
+	return s.bookLogStore.GetBookByIDAndUserID(int(next), userID)
+}
+
+// place gives bookID a sort key between its new neighbours in queue. Only that one row is written,
+// unless the key would get too long, then the whole queue is respaced.
+func (s *queueService) place(userID int, bookID uint, queue []model.QueueEntry, position int, add bool) error {
+	others := make([]model.QueueEntry, 0, len(queue))
+	found := false
+	for _, entry := range queue {
+		if entry.BookLogID == bookID {
+			found = true
+			continue
+		}
+		others = append(others, entry)
+	}
+	if !add && !found {
+		return gorm.ErrRecordNotFound
+	}
+	position = max(0, min(position, len(others)))
+
+	var prev, next string
+	if position > 0 {
+		prev = others[position-1].SortKey
+	}
+	if position < len(others) {
+		next = others[position].SortKey
+	}
+	key := sortKeyBetween(prev, next)
+
+	// equal neighbours only happen after concurrent writes, respacing sorts them out as well
+	if len(key) <= maxSortKeyLength && key > prev && (next == "" || key < next) {
+		if add {
+			return s.queueStore.UpsertEntry(&model.QueueEntry{UserID: uint(userID), BookLogID: bookID, SortKey: key})
+		}
+		return s.queueStore.UpdateSortKey(userID, bookID, key)
+	}
+
+	ids := make([]uint, 0, len(others)+1)
+	for i, entry := range others {
+		if i == position {
+			ids = append(ids, bookID)
+		}
+		ids = append(ids, entry.BookLogID)
+	}
+	if position == len(others) {
+		ids = append(ids, bookID)
+	}
+	keys := evenSortKeys(len(ids))
+	sortKeys := make(map[uint]string, len(ids))
+	for i, id := range ids {
+		sortKeys[id] = keys[i]
+	}
+	if add {
+		if err := s.queueStore.UpsertEntry(&model.QueueEntry{UserID: uint(userID), BookLogID: bookID, SortKey: sortKeys[bookID]}); err != nil {
+			return err
+		}
+	}
+	return s.queueStore.UpdateSortKeys(userID, sortKeys)
+}
+
+// sortKeyBetween returns a key that sorts strictly between a and b. Keys are base-26 fractions
+// written with sortKeyDigits, "" stands for the start of the queue as a and for its end as b.
+func sortKeyBetween(a, b string) string {
+	digitAt := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return sortKeyDigits[0]
+	}
+	if b != "" {
+		// keep the common prefix and find a key in the remainder
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + sortKeyBetween(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(sortKeyDigits, a[0])
+	}
+	hi := len(sortKeyDigits)
+	if b != "" {
+		hi = strings.IndexByte(sortKeyDigits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(sortKeyDigits[(lo+hi)/2])
+	}
+	// adjacent first digits: b's first digit alone is already between, or go one digit deeper after a
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(sortKeyDigits[lo]) + sortKeyBetween(rest, "")
+}
+
+// evenSortKeys returns n ascending keys spread evenly over the key space
+func evenSortKeys(n int) []string {
+	base := len(sortKeyDigits)
+	width, space := 1, base
+	for space < 2*(n+1) {
+		width++
+		space *= base
+	}
+	step := space / (n + 1)
+
+	keys := make([]string, n)
+	for i := range keys {
+		value := (i + 1) * step
+		digits := make([]byte, width)
+		for d := width - 1; d >= 0; d-- {
+			digits[d] = sortKeyDigits[value%base]
+			value /= base
+		}
+		// trailing zero digits do not change the value, and keys must not end with one
+		keys[i] = strings.TrimRight(string(digits), sortKeyDigits[:1])
+	}
+	return keys
+}
@@ -1,31 +1,293 @@
 package service
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
 	"project/internal/model"
 	"project/internal/store"
-	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSessionMinutes is the longest reading session that can be logged
+	maxSessionMinutes = 24 * 60
+	// sessionClockSkew is how far in the future a session may start, client clocks drift
+	sessionClockSkew = 5 * time.Minute
+
+	// maxReadBatchSize caps the sessions of one upload
+	maxReadBatchSize = 500
+	// syncSettleDelay keeps the newest changes out of a sync, writes that are still committing
+	// may carry an earlier updated_at than changes already handed out
+	syncSettleDelay = 2 * time.Second
 
+	ReadUploadCreated   = "created"
+	ReadUploadDuplicate = "duplicate"
+	ReadUploadDeleted   = "deleted"
+	ReadUploadInvalid   = "invalid"
 )
 
+// clientIDPattern matches a UUID in any letter case
+var clientIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ReadSessionInput is a reading session as clients log it. The session starts at StartedAt,
+// or at Date for older clients, and lasts Time minutes or until EndedAt.
+type ReadSessionInput struct {
+	Time      int        `json:"data"`
+	Date      *time.Time `json:"date"`
+	StartedAt *time.Time `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+	Pages     int        `json:"pages"`
+	BookID    *uint      `json:"bookId"`
+	// ClientID is a UUID generated by the client, it makes uploading the session again harmless
+	ClientID string `json:"clientId"`
+}
+
+// ReadBatchInput is a batch of sessions logged offline, each one needs a ClientID
+type ReadBatchInput struct {
+	Sessions []ReadSessionInput `json:"sessions" binding:"required"`
+}
+
+// ReadUploadResult is what became of one uploaded session. A duplicate returns the stored
+// session, a deleted one was stored before and deleted since, it is not brought back.
+type ReadUploadResult struct {
+	ClientID string      `json:"client_id"`
+	Status   string      `json:"status"`
+	Read     *model.Read `json:"read,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// ReadDeletion tells a client to drop a session it synced before
+type ReadDeletion struct {
+	ID        uint      `json:"id"`
+	ClientID  *string   `json:"client_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ReadChanges is one page of the change feed. Cursor is passed to the next sync, it stays
+// the same when nothing changed.
+type ReadChanges struct {
+	Reads   []model.Read   `json:"reads"`
+	Deleted []ReadDeletion `json:"deleted"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
+// ReadPeriod is the reading done in one week, month or year
+type ReadPeriod struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Minutes  int                `json:"minutes"`
+	Sessions int                `json:"sessions"`
+	Buckets  []store.ReadBucket `json:"buckets"`
+}
+
+// ReadTimeStats compares the current period with the one before. ChangePercent is nil
+// when nothing was read in the previous period.
+type ReadTimeStats struct {
+	Range         string     `json:"range"`
+	Bucket        string     `json:"bucket"`
+	Timezone      string     `json:"timezone"`
+	Current       ReadPeriod `json:"current"`
+	Previous      ReadPeriod `json:"previous"`
+	ChangeMinutes int        `json:"change_minutes"`
+	ChangePercent *float64   `json:"change_percent"`
+}
+
+// readRanges maps a stats range to the size of its buckets
+var readRanges = map[string]string{
+	"week":  "day",
+	"month": "week",
+	"year":  "month",
+}
+
+// ReadingStreaks are the streaks of a user with the minutes read per day of a year. A day counts
+// towards a streak with at least MinMinutes of reading, the current streak is still alive when
+// the last counted day was yesterday.
+type ReadingStreaks struct {
+	Timezone       string             `json:"timezone"`
+	MinMinutes     int                `json:"min_minutes"`
+	Current        *store.ReadStreak  `json:"current"`
+	Longest        *store.ReadStreak  `json:"longest"`
+	ReadToday      bool               `json:"read_today"`
+	Year           int                `json:"year"`
+	Heatmap        []store.ReadBucket `json:"heatmap"`
+	DaysReadInYear int                `json:"days_read_in_year"`
+}
+
+// ReadQuery selects reading sessions, from and to are YYYY-MM-DD and to is inclusive
+type ReadQuery struct {
+	BookID   *uint
+	From     string
+	To       string
+	Page     int
+	PageSize int
+}
+
 type ReadService interface {
-	CreateReadTime(userID int, read *model.Read) error
+	CreateReadTime(userID int, input ReadSessionInput) (*model.Read, error)
+	GetReadTime(readID int, userID int) (*model.Read, error)
+
+	// UpdateReadTime replaces a session with input, the same rules as for new sessions apply.
+	UpdateReadTime(readID int, userID int, input ReadSessionInput) (*model.Read, error)
+	DeleteReadTime(readID int, userID int) error
+	GetReadTimes(userID int, query ReadQuery) ([]model.Read, int64, error)
 	GetWeeklyReadTime(userID int) ([]model.Read, error)
+
+	// GetReadTimeStats totals the current calendar week, month or year in the timezone tz, or the
+	// user's one, per day, week or month, and compares it with the whole period before.
+	GetReadTimeStats(userID int, rangeName, tz string) (*ReadTimeStats, error)
+
+	// GetReadingStreaks returns the current and longest streak and the daily minutes of year,
+	// 0 for the current one. Days follow the user's timezone and streak threshold.
+	GetReadingStreaks(userID int, year int) (*ReadingStreaks, error)
+
+	// UploadReadTimes stores a batch of offline sessions. Sessions already uploaded are not stored
+	// again, invalid ones are reported without failing the rest of the batch.
+	UploadReadTimes(userID int, input ReadBatchInput) ([]ReadUploadResult, error)
+
+	// SyncReadTimes returns up to limit sessions created, changed or deleted since cursor. Without
+	// a cursor it starts from the beginning and leaves out deleted sessions.
+	SyncReadTimes(userID int, cursor string, limit int) (*ReadChanges, error)
 }
 
 type readService struct {
 	readTimeStore store.ReadTimeStore
+	bookLogStore  store.BookLogStore
+	userStore     store.UserStore
+}
+
+func NewReadService(readTimeStore store.ReadTimeStore, bookLogStore store.BookLogStore, userStore store.UserStore) ReadService {
+	return &readService{readTimeStore: readTimeStore, bookLogStore: bookLogStore, userStore: userStore}
+}
+
+// applyReadInput validates input and copies it onto read
+func (s *readService) applyReadInput(read *model.Read, userID int, input ReadSessionInput) error {
+	startedAt := input.StartedAt
+	if startedAt == nil {
+		startedAt = input.Date
+	}
+	if startedAt == nil || startedAt.IsZero() {
+		return invalidInput("startedAt or date is required")
+	}
+	if startedAt.After(time.Now().Add(sessionClockSkew)) {
+		return invalidInput("a reading session cannot start in the future")
+	}
+
+	minutes := input.Time
+	if input.EndedAt != nil {
+		if !input.EndedAt.After(*startedAt) {
+			return invalidInput("endedAt must be after the start of the session")
+		}
+		// an explicit duration wins, e.g. for sessions with breaks in between
+		if minutes == 0 {
+			minutes = int(math.Round(input.EndedAt.Sub(*startedAt).Minutes()))
+		}
+	}
+	if minutes <= 0 {
+		return invalidInput("the session needs a duration in minutes or an endedAt")
+	}
+	if minutes > maxSessionMinutes {
+		return invalidInput("a reading session can be at most %d minutes", maxSessionMinutes)
+	}
+	if input.Pages < 0 {
+		return invalidInput("pages cannot be negative")
+	}
+
+	if input.BookID != nil {
+		book, err := s.bookLogStore.GetBookByIDAndUserID(int(*input.BookID), userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidInput("book %d does not exist", *input.BookID)
+		}
+		if err != nil {
+			return err
+		}
+		read.BookLogID = &book.ID
+	} else {
+		read.BookLogID = nil
+	}
+
+	read.Time = minutes
+	read.StartedAt = *startedAt
+	read.EndedAt = input.EndedAt
+	read.Pages = input.Pages
+	return nil
 }
 
-func NewReadService(readTimeStore store.ReadTimeStore) ReadService {
-	return &readService{readTimeStore: readTimeStore}
+// newRead validates a session that is about to be created
+func (s *readService) newRead(userID int, input ReadSessionInput) (*model.Read, error) {
+	read := &model.Read{UserID: uint(userID)}
+	if input.ClientID != "" {
+		if !clientIDPattern.MatchString(input.ClientID) {
+			return nil, invalidInput("clientId must be a UUID")
+		}
+		clientID := strings.ToLower(input.ClientID)
+		read.ClientID = &clientID
+	}
+	if err := s.applyReadInput(read, userID, input); err != nil {
+		return nil, err
+	}
+	return read, nil
 }
 
-func (s *readService) CreateReadTime(userID int, read *model.Read) error {
-	if read == nil {
-		return errors.New("read cannot be nil")
+func (s *readService) CreateReadTime(userID int, input ReadSessionInput) (*model.Read, error) {
+	read, err := s.newRead(userID, input)
+	if err != nil {
+		return nil, err
 	}
-	read.UserID = uint(userID) // Set the UserID for the read time
-	return s.readTimeStore.CreateReadTime(userID, read)
+	err = s.readTimeStore.CreateReadTime(userID, read)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// a retry, answer with what the first attempt stored
+		stored, err := s.readTimeStore.GetReadTimesByClientIDs(userID, []string{*read.ClientID})
+		if err != nil {
+			return nil, err
+		}
+		if len(stored) == 0 || stored[0].DeletedAt.Valid {
+			return nil, invalidInput("the session %s was deleted", *read.ClientID)
+		}
+		read.ID = stored[0].ID
+	} else if err != nil {
+		return nil, err
+	}
+	return s.readTimeStore.GetReadTimeByID(int(read.ID), userID)
+}
+
+func (s *readService) GetReadTime(readID int, userID int) (*model.Read, error) {
+	return s.readTimeStore.GetReadTimeByID(readID, userID)
+}
+
+func (s *readService) UpdateReadTime(readID int, userID int, input ReadSessionInput) (*model.Read, error) {
+	read, err := s.readTimeStore.GetReadTimeByID(readID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyReadInput(read, userID, input); err != nil {
+		return nil, err
+	}
+	// the preloaded book would otherwise be written back by the update
+	read.BookLog = nil
+	if err := s.readTimeStore.UpdateReadTime(read); err != nil {
+		return nil, err
+	}
+	return s.readTimeStore.GetReadTimeByID(readID, userID)
+}
+
+func (s *readService) DeleteReadTime(readID int, userID int) error {
+	return s.readTimeStore.DeleteReadTime(readID, userID)
+}
+
+func (s *readService) GetReadTimes(userID int, query ReadQuery) ([]model.Read, int64, error) {
+	from, to, err := parseDateRange(query.From, query.To)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.readTimeStore.GetReadTimes(userID, store.ReadFilter{BookID: query.BookID, From: from, To: to}, query.Page, query.PageSize)
 }
 
 func (s *readService) GetWeeklyReadTime(userID int) ([]model.Read, error) {
@@ -34,4 +296,278 @@ func (s *readService) GetWeeklyReadTime(userID int) ([]model.Read, error) {
 		return nil, err
 	}
 	return reads, nil
-}
\ No newline at end of file
+}
+
+// loadTimezone resolves an IANA timezone name, an empty name is UTC
+func loadTimezone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, invalidInput("unknown timezone %q", tz)
+	}
+	return loc, nil
+}
+
+// periodStart is the start of the calendar week (from Monday), month or year containing t
+func periodStart(t time.Time, rangeName string) time.Time {
+	year, month, day := t.Date()
+	switch rangeName {
+	case "week":
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// addPeriods moves a period start n weeks, months or years
+func addPeriods(t time.Time, rangeName string, n int) time.Time {
+	switch rangeName {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+func (s *readService) readPeriod(userID int, unit string, loc *time.Location, from, to time.Time) (ReadPeriod, error) {
+	buckets, err := s.readTimeStore.GetReadTimeBuckets(userID, unit, loc, from, to)
+	if err != nil {
+		return ReadPeriod{}, err
+	}
+	period := ReadPeriod{
+		From:    from.Format("2006-01-02"),
+		To:      to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Buckets: buckets,
+	}
+	for _, bucket := range buckets {
+		period.Minutes += bucket.Minutes
+		period.Sessions += bucket.Sessions
+	}
+	return period, nil
+}
+
+func (s *readService) GetReadTimeStats(userID int, rangeName, tz string) (*ReadTimeStats, error) {
+	if rangeName == "" {
+		rangeName = "week"
+	}
+	unit, ok := readRanges[rangeName]
+	if !ok {
+		return nil, invalidInput("range must be week, month or year")
+	}
+	if tz == "" {
+		user, err := s.userStore.FindUserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		tz = user.Timezone
+	}
+	loc, err := loadTimezone(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	start := periodStart(time.Now().In(loc), rangeName)
+	end := addPeriods(start, rangeName, 1)
+	current, err := s.readPeriod(userID, unit, loc, start, end)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.readPeriod(userID, unit, loc, addPeriods(start, rangeName, -1), start)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ReadTimeStats{
+		Range:         rangeName,
+		Bucket:        unit,
+		Timezone:      loc.String(),
+		Current:       current,
+		Previous:      previous,
+		ChangeMinutes: current.Minutes - previous.Minutes,
+	}
+	if previous.Minutes > 0 {
+		percent := math.Round(float64(stats.ChangeMinutes)*1000/float64(previous.Minutes)) / 10
+		stats.ChangePercent = &percent
+	}
+	return stats, nil
+}
+
+func (s *readService) GetReadingStreaks(userID int, year int) (*ReadingStreaks, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadTimezone(user.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+	if year == 0 {
+		year = now.Year()
+	}
+	if year < 1900 || year > now.Year()+1 {
+		return nil, invalidInput("year must be between 1900 and %d", now.Year()+1)
+	}
+
+	streaks, err := s.readTimeStore.GetReadStreaks(userID, loc, user.StreakMinMinutes)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReadingStreaks{Timezone: loc.String(), MinMinutes: user.StreakMinMinutes, Year: year}
+	for i := range streaks {
+		// the latest of equally long streaks wins
+		if result.Longest == nil || streaks[i].Days >= result.Longest.Days {
+			result.Longest = &streaks[i]
+		}
+	}
+	if len(streaks) > 0 {
+		today := now.Format("2006-01-02")
+		last := &streaks[len(streaks)-1]
+		result.ReadToday = last.End == today
+		if result.ReadToday || last.End == now.AddDate(0, 0, -1).Format("2006-01-02") {
+			result.Current = last
+		}
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	result.Heatmap, err = s.readTimeStore.GetReadTimeBuckets(userID, "day", loc, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range result.Heatmap {
+		if day.Minutes >= user.StreakMinMinutes {
+			result.DaysReadInYear++
+		}
+	}
+	return result, nil
+}
+
+func (s *readService) UploadReadTimes(userID int, input ReadBatchInput) ([]ReadUploadResult, error) {
+	if len(input.Sessions) == 0 || len(input.Sessions) > maxReadBatchSize {
+		return nil, invalidInput("upload between 1 and %d sessions", maxReadBatchSize)
+	}
+
+	results := make([]ReadUploadResult, len(input.Sessions))
+	reads := make([]*model.Read, 0, len(input.Sessions))
+	// index of the result each read belongs to
+	positions := make([]int, 0, len(input.Sessions))
+	for i, session := range input.Sessions {
+		results[i].ClientID = session.ClientID
+		if session.ClientID == "" {
+			results[i].Status, results[i].Error = ReadUploadInvalid, "clientId is required"
+			continue
+		}
+		read, err := s.newRead(userID, session)
+		if errors.Is(err, ErrInvalidInput) {
+			results[i].Status, results[i].Error = ReadUploadInvalid, err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[i].ClientID = *read.ClientID
+		reads = append(reads, read)
+		positions = append(positions, i)
+	}
+
+	created, err := s.readTimeStore.CreateReadTimes(reads)
+	if err != nil {
+		return nil, err
+	}
+	var duplicates []string
+	for j, read := range reads {
+		if created[j] {
+			results[positions[j]].Status, results[positions[j]].Read = ReadUploadCreated, read
+		} else {
+			duplicates = append(duplicates, *read.ClientID)
+		}
+	}
+
+	stored, err := s.readTimeStore.GetReadTimesByClientIDs(userID, duplicates)
+	if err != nil {
+		return nil, err
+	}
+	byClientID := make(map[string]*model.Read, len(stored))
+	for i := range stored {
+		byClientID[*stored[i].ClientID] = &stored[i]
+	}
+	for j, read := range reads {
+		if created[j] {
+			continue
+		}
+		result := &results[positions[j]]
+		previous, ok := byClientID[*read.ClientID]
+		switch {
+		case !ok:
+			// only a hard-deleted session would be missing
+			result.Status = ReadUploadDeleted
+		case previous.DeletedAt.Valid:
+			result.Status = ReadUploadDeleted
+		default:
+			result.Status, result.Read = ReadUploadDuplicate, previous
+		}
+	}
+	return results, nil
+}
+
+// encodeReadCursor makes an opaque cursor pointing after read
+func encodeReadCursor(read *model.Read) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", read.UpdatedAt.UnixMicro(), read.ID)))
+}
+
+func decodeReadCursor(cursor string) (*store.ReadCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalidInput("invalid cursor")
+	}
+	micros, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, invalidInput("invalid cursor")
+	}
+	at, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, invalidInput("invalid cursor")
+	}
+	readID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, invalidInput("invalid cursor")
+	}
+	return &store.ReadCursor{UpdatedAt: time.UnixMicro(at), ID: uint(readID)}, nil
+}
+
+func (s *readService) SyncReadTimes(userID int, cursor string, limit int) (*ReadChanges, error) {
+	var after *store.ReadCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeReadCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// one extra row tells whether there is more
+	reads, err := s.readTimeStore.GetReadTimeChanges(userID, after, time.Now().Add(-syncSettleDelay), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	changes := &ReadChanges{Reads: []model.Read{}, Deleted: []ReadDeletion{}, Cursor: cursor}
+	if len(reads) > limit {
+		reads, changes.HasMore = reads[:limit], true
+	}
+	for _, read := range reads {
+		if !read.DeletedAt.Valid {
+			changes.Reads = append(changes.Reads, read)
+		} else if after != nil {
+			changes.Deleted = append(changes.Deleted, ReadDeletion{ID: read.ID, ClientID: read.ClientID, DeletedAt: read.DeletedAt.Time})
+		}
+	}
+	if len(reads) > 0 {
+		changes.Cursor = encodeReadCursor(&reads[len(reads)-1])
+	}
+	return changes, nil
+}
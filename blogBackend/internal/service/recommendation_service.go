@@ -0,0 +1,312 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"project/internal/model"
+	"project/internal/store"
+	"sort"
+	"strings"
+	"time"
+)
+
+type RecommendationService interface {
+	GetRecommendations(userID int, limit int) ([]model.Recommendation, error)
+
+	// Refresh rebuilds the recommendations of every user from the current book logs.
+	Refresh() error
+
+	// StartRefresher runs Refresh now and then every interval, it blocks and is meant to run in a goroutine.
+	StartRefresher(interval time.Duration)
+}
+
+type recommendationService struct {
+	recommendationStore store.RecommendationStore
+	bookLogStore        store.BookLogStore
+}
+
+func NewRecommendationService(recommendationStore store.RecommendationStore, bookLogStore store.BookLogStore) RecommendationService {
+	return &recommendationService{recommendationStore: recommendationStore, bookLogStore: bookLogStore}
+}
+
+const (
+	// recommendationsPerUser is how many suggestions are stored for each user
+	recommendationsPerUser = 30
+	// maxItemsPerUser caps the co-occurrence pairs a single huge library contributes
+	maxItemsPerUser = 300
+	// categoryWeight scales category affinity against co-occurrence scores
+	categoryWeight = 0.3
+)
+
+// recItem is a book identified across users, by ISBN or by title+author
+type recItem struct {
+	id       int
+	title    string
+	author   string
+	isbn     string
+	category string
+	coverUrl string
+	readers  int
+	// shared is set once a book log of the item is not private, only shared items are recommended
+	shared bool
+}
+
+// userItem is how much one user cares about one item, private items only count for the user themselves
+type userItem struct {
+	item    int
+	weight  float64
+	private bool
+}
+
+func (s *recommendationService) GetRecommendations(userID int, limit int) ([]model.Recommendation, error) {
+	return s.recommendationStore.GetByUserID(userID, limit)
+}
+
+func (s *recommendationService) StartRefresher(interval time.Duration) {
+	for {
+		start := time.Now()
+		if err := s.Refresh(); err != nil {
+			log.Printf("Recommendations - refresh failed: %v", err)
+		} else {
+			log.Printf("Recommendations - refreshed in %s", time.Since(start))
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (s *recommendationService) Refresh() error {
+	items := []*recItem{}
+	itemByKey := make(map[string]int)
+	libraries := make(map[uint][]userItem)
+
+	err := s.bookLogStore.IterateAllBookLogs(500, func(books []model.BookLog) error {
+		for _, book := range books {
+			// books the user didn't like still belong to their library, so they are not recommended back
+			weight := interestWeight(book)
+
+			// link every key of the book to the same item so ISBN and title matches merge
+			keys := bookDedupeKeys(book.ISBN, book.Title, book.Author)
+			if len(keys) == 0 {
+				continue
+			}
+			id, found := -1, false
+			for _, key := range keys {
+				if id, found = itemByKey[key]; found {
+					break
+				}
+			}
+			if !found {
+				id = len(items)
+				items = append(items, &recItem{
+					id:       id,
+					title:    book.Title,
+					author:   book.Author,
+					isbn:     book.ISBN,
+					category: book.Category,
+					coverUrl: book.CoverUrl,
+				})
+			}
+			for _, key := range keys {
+				itemByKey[key] = id
+			}
+			item := items[id]
+			private := book.Visibility == model.VisibilityPrivate
+			if !private {
+				// what other users get to see comes from shared book logs only
+				if !item.shared {
+					item.title, item.author, item.isbn = book.Title, book.Author, book.ISBN
+					item.category, item.coverUrl = book.Category, book.CoverUrl
+					item.shared = true
+				}
+				if item.category == "" {
+					item.category = book.Category
+				}
+				if item.coverUrl == "" {
+					item.coverUrl = book.CoverUrl
+				}
+				if weight > 0 {
+					item.readers++
+				}
+			}
+			libraries[book.UserID] = append(libraries[book.UserID], userItem{item: id, weight: weight, private: private})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// users without any book left to go on lose their old recommendations too
+	stale, err := s.recommendationStore.GetUserIDs()
+	if err != nil {
+		return err
+	}
+	for _, userID := range stale {
+		if _, ok := libraries[userID]; !ok {
+			if err := s.recommendationStore.ReplaceForUser(int(userID), nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	cooc := buildCooccurrence(items, libraries)
+	now := time.Now()
+	for userID, library := range libraries {
+		recs := recommendFor(library, items, cooc, now)
+		for i := range recs {
+			recs[i].UserID = userID
+		}
+		if err := s.recommendationStore.ReplaceForUser(int(userID), recs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interestWeight turns a book log into an interest signal in [0, 1].
+// Ratings dominate, an unrated book still counts a little, and a low rating is negative evidence
+// that is dropped rather than propagated.
+func interestWeight(book model.BookLog) float64 {
+	if book.MyRating != nil && *book.MyRating > 0 {
+		if *book.MyRating <= 2 {
+			return 0
+		}
+		return float64(*book.MyRating) / 5
+	}
+	switch book.Status {
+	case model.BookStatusRead, model.BookStatusReading:
+		return 0.6
+	case model.BookStatusDidNotFinish:
+		return 0
+	default:
+		return 0.3
+	}
+}
+
+// buildCooccurrence returns item-to-item similarity: rating-weighted co-occurrence
+// normalized by how many readers each item has, so bestsellers don't dominate.
+func buildCooccurrence(items []*recItem, libraries map[uint][]userItem) map[int]map[int]float64 {
+	cooc := make(map[int]map[int]float64)
+	for _, library := range libraries {
+		liked := make([]userItem, 0, len(library))
+		for _, ui := range library {
+			if ui.weight > 0 && !ui.private {
+				liked = append(liked, ui)
+			}
+		}
+		if len(liked) > maxItemsPerUser {
+			liked = liked[len(liked)-maxItemsPerUser:]
+		}
+		for i, a := range liked {
+			for _, b := range liked[i+1:] {
+				if a.item == b.item {
+					continue
+				}
+				w := a.weight * b.weight
+				if cooc[a.item] == nil {
+					cooc[a.item] = make(map[int]float64)
+				}
+				if cooc[b.item] == nil {
+					cooc[b.item] = make(map[int]float64)
+				}
+				cooc[a.item][b.item] += w
+				cooc[b.item][a.item] += w
+			}
+		}
+	}
+	for a, neighbours := range cooc {
+		for b, w := range neighbours {
+			neighbours[b] = w / math.Sqrt(float64(items[a].readers*items[b].readers))
+		}
+	}
+	return cooc
+}
+
+func recommendFor(library []userItem, items []*recItem, cooc map[int]map[int]float64, now time.Time) []model.Recommendation {
+	owned := make(map[int]bool)
+	categoryWeights := make(map[string]float64)
+	totalWeight := 0.0
+	for _, ui := range library {
+		owned[ui.item] = true
+		if category := strings.ToLower(items[ui.item].category); category != "" {
+			categoryWeights[category] += ui.weight
+			totalWeight += ui.weight
+		}
+	}
+
+	scores := make(map[int]float64)
+	// the owned item that contributed most to each candidate, for the explanation
+	because := make(map[int]int)
+	becauseScore := make(map[int]float64)
+	for _, ui := range library {
+		for candidate, sim := range cooc[ui.item] {
+			if owned[candidate] || !items[candidate].shared {
+				continue
+			}
+			contribution := sim * ui.weight
+			scores[candidate] += contribution
+			if contribution > becauseScore[candidate] {
+				because[candidate] = ui.item
+				becauseScore[candidate] = contribution
+			}
+		}
+	}
+
+	// category affinity: share of the user's interest in the candidate's category
+	affinity := func(item *recItem) float64 {
+		if totalWeight == 0 || item.category == "" {
+			return 0
+		}
+		return categoryWeights[strings.ToLower(item.category)] / totalWeight
+	}
+
+	// books from the user's favourite categories are candidates even without co-readers
+	for _, item := range items {
+		if owned[item.id] || !item.shared {
+			continue
+		}
+		if a := affinity(item); a > 0 {
+			popularity := math.Log1p(float64(item.readers)) / math.Log1p(float64(len(items)))
+			scores[item.id] += categoryWeight * a * popularity
+		}
+	}
+
+	candidates := make([]int, 0, len(scores))
+	for id, score := range scores {
+		if score > 0 {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	if len(candidates) > recommendationsPerUser {
+		candidates = candidates[:recommendationsPerUser]
+	}
+
+	recs := make([]model.Recommendation, 0, len(candidates))
+	for _, id := range candidates {
+		item := items[id]
+		explanation := ""
+		if source, ok := because[id]; ok {
+			explanation = fmt.Sprintf("Readers who liked %s also liked this", items[source].title)
+		} else {
+			explanation = fmt.Sprintf("Popular in %s, a category you read a lot", item.category)
+		}
+		recs = append(recs, model.Recommendation{
+			Title:       item.title,
+			Author:      item.author,
+			ISBN:        item.isbn,
+			Category:    item.category,
+			CoverUrl:    item.coverUrl,
+			Score:       scores[id],
+			Explanation: explanation,
+			GeneratedAt: now,
+		})
+	}
+	return recs
+}
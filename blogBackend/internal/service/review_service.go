@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReviewFeedItem is a published review as other users see it
+type ReviewFeedItem struct {
+	ID           uint             `json:"id"`
+	BookLogID    uint             `json:"book_log_id"`
+	Title        string           `json:"title"`
+	Author       string           `json:"author"`
+	CoverUrl     string           `json:"cover_url"`
+	Rating       *int             `json:"rating"`
+	Review       string           `json:"review"`
+	PublishedAt  time.Time        `json:"published_at"`
+	LikeCount    int              `json:"like_count"`
+	CommentCount int              `json:"comment_count"`
+	User         model.PublicUser `json:"user"`
+}
+
+// ReviewCommentItem is a comment on a review with its author's public profile
+type ReviewCommentItem struct {
+	ID        uint             `json:"id"`
+	Content   string           `json:"content"`
+	CreatedAt time.Time        `json:"created_at"`
+	User      model.PublicUser `json:"user"`
+}
+
+type ReviewService interface {
+	PublishReview(bookID int, userID int) (*ReviewFeedItem, error)
+	UnpublishReview(bookID int, userID int) error
+	// viewerID is 0 for anonymous visitors, reviews of book logs they may not see are not found
+	GetReview(reviewID int, viewerID int) (*ReviewFeedItem, error)
+	GetReviews(viewerID int, sort string, page, pageSize int) ([]ReviewFeedItem, int64, error)
+	LikeReview(reviewID int, userID int) (*ReviewFeedItem, error)
+	UnlikeReview(reviewID int, userID int) (*ReviewFeedItem, error)
+	CreateComment(reviewID int, userID int, content string) (*ReviewCommentItem, error)
+	DeleteComment(commentID int, reviewID int, userID int) error
+	GetComments(reviewID int, viewerID int, page, pageSize int) ([]ReviewCommentItem, int64, error)
+}
+
+type reviewService struct {
+	reviewStore  store.ReviewStore
+	bookLogStore store.BookLogStore
+}
+
+func NewReviewService(reviewStore store.ReviewStore, bookLogStore store.BookLogStore) ReviewService {
+	return &reviewService{reviewStore: reviewStore, bookLogStore: bookLogStore}
+}
+
+func newReviewFeedItem(review model.Review) ReviewFeedItem {
+	return ReviewFeedItem{
+		ID:           review.ID,
+		BookLogID:    review.BookLogID,
+		Title:        review.BookLog.Title,
+		Author:       review.BookLog.Author,
+		CoverUrl:     review.BookLog.CoverUrl,
+		Rating:       review.BookLog.MyRating,
+		Review:       review.BookLog.MyComment,
+		PublishedAt:  review.PublishedAt,
+		LikeCount:    review.LikeCount,
+		CommentCount: review.CommentCount,
+		User:         review.User.Public(),
+	}
+}
+
+func newReviewCommentItem(comment model.ReviewComment) ReviewCommentItem {
+	return ReviewCommentItem{
+		ID:        comment.ID,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+		User:      comment.User.Public(),
+	}
+}
+
+func (s *reviewService) PublishReview(bookID int, userID int) (*ReviewFeedItem, error) {
+	book, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(book.MyComment) == "" && book.MyRating == nil {
+		return nil, invalidInput("write a review or rate the book before publishing")
+	}
+	if book.Visibility == model.VisibilityPrivate {
+		return nil, invalidInput("the book is private, share it with followers or make it public before publishing")
+	}
+
+	review, err := s.reviewStore.GetReviewByBookLogID(bookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		review = &model.Review{
+			BookLogID:   book.ID,
+			UserID:      book.UserID,
+			PublishedAt: time.Now(),
+		}
+		err = s.reviewStore.CreateReview(review)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReview(int(review.ID), userID)
+}
+
+func (s *reviewService) UnpublishReview(bookID int, userID int) error {
+	return s.reviewStore.DeleteReviewByBookLogID(bookID, userID)
+}
+
+func (s *reviewService) GetReview(reviewID int, viewerID int) (*ReviewFeedItem, error) {
+	review, err := s.reviewStore.GetReviewByID(reviewID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	item := newReviewFeedItem(*review)
+	return &item, nil
+}
+
+func (s *reviewService) GetReviews(viewerID int, sort string, page, pageSize int) ([]ReviewFeedItem, int64, error) {
+	if sort != store.ReviewSortPopular {
+		sort = store.ReviewSortRecent
+	}
+	reviews, total, err := s.reviewStore.GetReviews(viewerID, sort, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]ReviewFeedItem, 0, len(reviews))
+	for _, review := range reviews {
+		items = append(items, newReviewFeedItem(review))
+	}
+	return items, total, nil
+}
+
+func (s *reviewService) LikeReview(reviewID int, userID int) (*ReviewFeedItem, error) {
+	review, err := s.reviewStore.GetReviewByID(reviewID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID == uint(userID) {
+		return nil, invalidInput("you cannot like your own review")
+	}
+	if _, err := s.reviewStore.LikeReview(reviewID, userID); err != nil {
+		return nil, err
+	}
+	return s.GetReview(reviewID, userID)
+}
+
+func (s *reviewService) UnlikeReview(reviewID int, userID int) (*ReviewFeedItem, error) {
+	if _, err := s.reviewStore.UnlikeReview(reviewID, userID); err != nil {
+		return nil, err
+	}
+	return s.GetReview(reviewID, userID)
+}
+
+func (s *reviewService) CreateComment(reviewID int, userID int, content string) (*ReviewCommentItem, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, invalidInput("comment cannot be empty")
+	}
+	// the review must exist and still be published
+	if _, err := s.reviewStore.GetReviewByID(reviewID, userID); err != nil {
+		return nil, err
+	}
+
+	comment := &model.ReviewComment{
+		ReviewID: uint(reviewID),
+		UserID:   uint(userID),
+		Content:  content,
+	}
+	if err := s.reviewStore.CreateComment(comment); err != nil {
+		return nil, err
+	}
+	item := newReviewCommentItem(*comment)
+	return &item, nil
+}
+
+func (s *reviewService) DeleteComment(commentID int, reviewID int, userID int) error {
+	return s.reviewStore.DeleteComment(commentID, reviewID, userID)
+}
+
+func (s *reviewService) GetComments(reviewID int, viewerID int, page, pageSize int) ([]ReviewCommentItem, int64, error) {
+	if _, err := s.reviewStore.GetReviewByID(reviewID, viewerID); err != nil {
+		return nil, 0, err
+	}
+	comments, total, err := s.reviewStore.GetComments(reviewID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]ReviewCommentItem, 0, len(comments))
+	for _, comment := range comments {
+		items = append(items, newReviewCommentItem(comment))
+	}
+	return items, total, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"fmt"
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+	"unicode/utf8"
+)
+
+type SeriesInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type SeriesBookInput struct {
+	BookID int `json:"bookId" binding:"required"`
+	// Position defaults to the end of the series
+	Position *float64 `json:"position"`
+}
+
+type SeriesService interface {
+	CreateSeries(userID int, input SeriesInput) (*model.Series, error)
+	GetSeriesList(userID int) ([]store.SeriesSummary, error)
+	GetSeries(seriesID int, userID int) (*model.Series, error)
+	UpdateSeries(seriesID int, userID int, input SeriesInput) (*model.Series, error)
+	DeleteSeries(seriesID int, userID int) error
+
+	// AddBook puts a book log into a series, moving it out of any other series.
+	AddBook(seriesID int, userID int, input SeriesBookInput) (*model.Series, error)
+	RemoveBook(seriesID int, userID int, bookID int) error
+
+	// ReorderBooks renumbers the series in the given order, every book of the series must be listed.
+	// Editions of one volume, the same work or the same position, keep sharing the number of the first listed.
+	ReorderBooks(seriesID int, userID int, bookIDs []uint) (*model.Series, error)
+}
+
+type seriesService struct {
+	seriesStore  store.SeriesStore
+	bookLogStore store.BookLogStore
+}
+
+func NewSeriesService(seriesStore store.SeriesStore, bookLogStore store.BookLogStore) SeriesService {
+	return &seriesService{seriesStore: seriesStore, bookLogStore: bookLogStore}
+}
+
+func validateSeriesInput(input SeriesInput) (string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return "", invalidInput("name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return "", invalidInput("name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+func (s *seriesService) CreateSeries(userID int, input SeriesInput) (*model.Series, error) {
+	name, err := validateSeriesInput(input)
+	if err != nil {
+		return nil, err
+	}
+	series := &model.Series{UserID: uint(userID), Name: name, Description: input.Description}
+	if err := s.seriesStore.CreateSeries(series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (s *seriesService) GetSeriesList(userID int) ([]store.SeriesSummary, error) {
+	return s.seriesStore.GetSeriesList(userID)
+}
+
+func (s *seriesService) GetSeries(seriesID int, userID int) (*model.Series, error) {
+	return s.seriesStore.GetSeriesByID(seriesID, userID)
+}
+
+func (s *seriesService) UpdateSeries(seriesID int, userID int, input SeriesInput) (*model.Series, error) {
+	name, err := validateSeriesInput(input)
+	if err != nil {
+		return nil, err
+	}
+	series := &model.Series{Name: name, Description: input.Description, UserID: uint(userID)}
+	series.ID = uint(seriesID)
+	if err := s.seriesStore.UpdateSeries(series); err != nil {
+		return nil, err
+	}
+	return s.seriesStore.GetSeriesByID(seriesID, userID)
+}
+
+func (s *seriesService) DeleteSeries(seriesID int, userID int) error {
+	return s.seriesStore.DeleteSeries(seriesID, userID)
+}
+
+func (s *seriesService) AddBook(seriesID int, userID int, input SeriesBookInput) (*model.Series, error) {
+	series, err := s.seriesStore.GetSeriesByID(seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+	position := 0.0
+	if input.Position != nil {
+		if *input.Position <= 0 {
+			return nil, invalidInput("position must be positive")
+		}
+		position = *input.Position
+	} else {
+		max, err := s.seriesStore.GetMaxPosition(series.ID)
+		if err != nil {
+			return nil, err
+		}
+		position = float64(int(max)) + 1
+	}
+	fields := map[string]interface{}{"series_id": series.ID, "series_position": position}
+	if err := s.bookLogStore.PatchLog(input.BookID, userID, fields); err != nil {
+		return nil, err
+	}
+	return s.seriesStore.GetSeriesByID(seriesID, userID)
+}
+
+func (s *seriesService) RemoveBook(seriesID int, userID int, bookID int) error {
+	book, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+	if err != nil {
+		return err
+	}
+	if book.SeriesID == nil || *book.SeriesID != uint(seriesID) {
+		return invalidInput("book %d is not in series %d", bookID, seriesID)
+	}
+	return s.bookLogStore.PatchLog(bookID, userID, map[string]interface{}{"series_id": nil, "series_position": nil})
+}
+
+func (s *seriesService) ReorderBooks(seriesID int, userID int, bookIDs []uint) (*model.Series, error) {
+	series, err := s.seriesStore.GetSeriesByID(seriesID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bookIDs) != len(series.Books) {
+		return nil, invalidInput("the series has %d books, got %d ids", len(series.Books), len(bookIDs))
+	}
+	inSeries := make(map[uint]*model.BookLog, len(series.Books))
+	for i := range series.Books {
+		inSeries[series.Books[i].ID] = &series.Books[i]
+	}
+
+	// a book joins the volume of an earlier listed edition, otherwise it starts the next volume
+	positions := make(map[uint]float64, len(bookIDs))
+	volumes := make(map[string]float64)
+	next := 0.0
+	for _, id := range bookIDs {
+		book, ok := inSeries[id]
+		if !ok {
+			return nil, invalidInput("book %d is not in the series or listed twice", id)
+		}
+		delete(inSeries, id)
+
+		var keys []string
+		if book.WorkID != nil {
+			keys = append(keys, fmt.Sprintf("work:%d", *book.WorkID))
+		}
+		if book.SeriesPosition != nil {
+			keys = append(keys, fmt.Sprintf("position:%g", *book.SeriesPosition))
+		}
+		position, found := 0.0, false
+		for _, key := range keys {
+			if position, found = volumes[key]; found {
+				break
+			}
+		}
+		if !found {
+			next++
+			position = next
+		}
+		for _, key := range keys {
+			volumes[key] = position
+		}
+		positions[id] = position
+	}
+	if err := s.seriesStore.ReorderBooks(series.ID, userID, positions); err != nil {
+		return nil, err
+	}
+	return s.seriesStore.GetSeriesByID(seriesID, userID)
+}
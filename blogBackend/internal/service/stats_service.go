@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"project/internal/store"
+	"time"
+)
+
+// LibraryStatsReport is the library statistics with the range they were computed for
+type LibraryStatsReport struct {
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+	store.LibraryStats
+}
+
+type StatsService interface {
+	// GetLibraryStats accepts an optional YYYY-MM-DD range, both ends inclusive. Results are cached briefly,
+	// until the user changes a book log.
+	GetLibraryStats(userID int, from, to string) (*LibraryStatsReport, error)
+}
+
+type statsService struct {
+	statsStore store.StatsStore
+	cache      *ttlCache[*LibraryStatsReport]
+}
+
+func NewStatsService(statsStore store.StatsStore, cacheTTL time.Duration) StatsService {
+	return &statsService{statsStore: statsStore, cache: newTTLCache[*LibraryStatsReport](cacheTTL)}
+}
+
+func (s *statsService) GetLibraryStats(userID int, from, to string) (*LibraryStatsReport, error) {
+	fromTime, toTime, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last change is part of the key, so an edited library is computed again
+	version, err := s.statsStore.GetLibraryVersion(userID)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d|%s|%s|%d", userID, from, to, version.UnixNano())
+	if report, ok := s.cache.get(key); ok {
+		return report, nil
+	}
+	stats, err := s.statsStore.GetLibraryStats(userID, fromTime, toTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// every rating from 1 to 5 is listed, also the ones nobody gave
+	counts := make(map[int]int64, len(stats.RatingDistribution))
+	for _, bucket := range stats.RatingDistribution {
+		counts[bucket.Rating] = bucket.Count
+	}
+	stats.RatingDistribution = make([]store.RatingCount, 0, 5)
+	for rating := 1; rating <= 5; rating++ {
+		stats.RatingDistribution = append(stats.RatingDistribution, store.RatingCount{Rating: rating, Count: counts[rating]})
+	}
+
+	report := &LibraryStatsReport{From: from, To: to, GeneratedAt: time.Now(), LibraryStats: *stats}
+	s.cache.set(key, report)
+	return report, nil
+}
+
+// parseDateRange turns optional YYYY-MM-DD bounds into [from, to), the whole last day is included
+func parseDateRange(from, to string) (*time.Time, *time.Time, error) {
+	var fromTime, toTime *time.Time
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return nil, nil, invalidInput("from must be a YYYY-MM-DD date")
+		}
+		fromTime = &t
+	}
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return nil, nil, invalidInput("to must be a YYYY-MM-DD date")
+		}
+		t = t.AddDate(0, 0, 1)
+		toTime = &t
+	}
+	if fromTime != nil && toTime != nil && !fromTime.Before(*toTime) {
+		return nil, nil, invalidInput("from must not be after to")
+	}
+	return fromTime, toTime, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math"
+	"project/internal/model"
+	"project/internal/store"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimerStartInput optionally ties a new timer to a book
+type TimerStartInput struct {
+	BookID *uint `json:"bookId"`
+}
+
+// TimerStopInput is what the client knows only at the end of a session
+type TimerStopInput struct {
+	Pages int `json:"pages"`
+}
+
+// ReadTimerState is a timer with the time read so far
+type ReadTimerState struct {
+	*model.ReadTimer
+	Running        bool `json:"running"`
+	ElapsedSeconds int  `json:"elapsed_seconds"`
+}
+
+type TimerService interface {
+	GetTimer(userID int) (*ReadTimerState, error)
+	StartTimer(userID int, input TimerStartInput) (*ReadTimerState, error)
+	PauseTimer(userID int) (*ReadTimerState, error)
+	ResumeTimer(userID int) (*ReadTimerState, error)
+
+	// StopTimer ends the timer and records it as a reading session. Sessions shorter than
+	// a minute are dropped, the returned read is nil then.
+	StopTimer(userID int, input TimerStopInput) (*model.Read, error)
+	DiscardTimer(userID int) error
+
+	// CloseStaleTimers records and removes the timers that ran longer than the limit or
+	// were left paused that long. Sessions are cut off at the limit.
+	CloseStaleTimers() error
+
+	// StartStaleTimerCloser runs CloseStaleTimers now and then every interval, it blocks and is meant to run in a goroutine.
+	StartStaleTimerCloser(interval time.Duration)
+}
+
+type timerService struct {
+	timerStore   store.TimerStore
+	bookLogStore store.BookLogStore
+	readService  ReadService
+	maxDuration  time.Duration
+}
+
+func NewTimerService(timerStore store.TimerStore, bookLogStore store.BookLogStore, readService ReadService, maxDuration time.Duration) TimerService {
+	// a timer can't outlast the longest session it is recorded as
+	if maxDuration <= 0 || maxDuration > maxSessionMinutes*time.Minute {
+		maxDuration = maxSessionMinutes * time.Minute
+	}
+	return &timerService{timerStore: timerStore, bookLogStore: bookLogStore, readService: readService, maxDuration: maxDuration}
+}
+
+// elapsed is how long the timer ran until now
+func elapsed(timer *model.ReadTimer, now time.Time) time.Duration {
+	total := time.Duration(timer.AccumulatedSeconds) * time.Second
+	if timer.ResumedAt != nil && now.After(*timer.ResumedAt) {
+		total += now.Sub(*timer.ResumedAt)
+	}
+	return total
+}
+
+func timerState(timer *model.ReadTimer) *ReadTimerState {
+	return &ReadTimerState{
+		ReadTimer:      timer,
+		Running:        timer.ResumedAt != nil,
+		ElapsedSeconds: int(elapsed(timer, time.Now()) / time.Second),
+	}
+}
+
+// runningTimer gets the timer of the user, a missing one is reported as invalid input
+func (s *timerService) runningTimer(userID int) (*model.ReadTimer, error) {
+	timer, err := s.timerStore.GetTimer(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidInput("no reading timer is running")
+	}
+	return timer, err
+}
+
+func (s *timerService) GetTimer(userID int) (*ReadTimerState, error) {
+	timer, err := s.timerStore.GetTimer(userID)
+	if err != nil {
+		return nil, err
+	}
+	return timerState(timer), nil
+}
+
+func (s *timerService) StartTimer(userID int, input TimerStartInput) (*ReadTimerState, error) {
+	now := time.Now()
+	timer := &model.ReadTimer{UserID: uint(userID), StartedAt: now, ResumedAt: &now}
+	if input.BookID != nil {
+		book, err := s.bookLogStore.GetBookByIDAndUserID(int(*input.BookID), userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("book %d does not exist", *input.BookID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		timer.BookLogID = &book.ID
+	}
+	if err := s.timerStore.CreateTimer(timer); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, invalidInput("a reading timer is already running, stop it first")
+		}
+		return nil, err
+	}
+	return s.GetTimer(userID)
+}
+
+func (s *timerService) PauseTimer(userID int) (*ReadTimerState, error) {
+	timer, err := s.runningTimer(userID)
+	if err != nil {
+		return nil, err
+	}
+	if timer.ResumedAt == nil {
+		return nil, invalidInput("the reading timer is already paused")
+	}
+	now := time.Now()
+	timer.AccumulatedSeconds = int(elapsed(timer, now) / time.Second)
+	timer.ResumedAt = nil
+	timer.PausedAt = &now
+	if err := s.timerStore.UpdateTimer(timer); err != nil {
+		return nil, err
+	}
+	return timerState(timer), nil
+}
+
+func (s *timerService) ResumeTimer(userID int) (*ReadTimerState, error) {
+	timer, err := s.runningTimer(userID)
+	if err != nil {
+		return nil, err
+	}
+	if timer.ResumedAt != nil {
+		return nil, invalidInput("the reading timer is not paused")
+	}
+	now := time.Now()
+	timer.ResumedAt = &now
+	timer.PausedAt = nil
+	if err := s.timerStore.UpdateTimer(timer); err != nil {
+		return nil, err
+	}
+	return timerState(timer), nil
+}
+
+// record saves the timer as a session of length read that ended at endedAt and removes it
+func (s *timerService) record(timer *model.ReadTimer, read time.Duration, endedAt time.Time, pages int) (*model.Read, error) {
+	if read > s.maxDuration {
+		read = s.maxDuration
+	}
+	var session *model.Read
+	if minutes := int(math.Round(read.Minutes())); minutes > 0 {
+		// the book may have been deleted while the timer ran, the minutes still count without it
+		bookID := timer.BookLogID
+		if bookID != nil {
+			_, err := s.bookLogStore.GetBookByIDAndUserID(int(*bookID), int(timer.UserID))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				bookID = nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		session, err = s.readService.CreateReadTime(int(timer.UserID), ReadSessionInput{
+			Time:      minutes,
+			StartedAt: &timer.StartedAt,
+			EndedAt:   &endedAt,
+			Pages:     pages,
+			BookID:    bookID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the timer only goes once its session is saved. Whoever removes it keeps the session,
+	// a second stop from another device finds nothing and takes its copy back.
+	if err := s.timerStore.DeleteTimer(timer.ID); err != nil {
+		if session != nil {
+			if err := s.readService.DeleteReadTime(int(session.ID), int(timer.UserID)); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInput("no reading timer is running")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *timerService) StopTimer(userID int, input TimerStopInput) (*model.Read, error) {
+	if input.Pages < 0 {
+		return nil, invalidInput("pages cannot be negative")
+	}
+	timer, err := s.runningTimer(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return s.record(timer, elapsed(timer, now), now, input.Pages)
+}
+
+func (s *timerService) DiscardTimer(userID int) error {
+	timer, err := s.timerStore.GetTimer(userID)
+	if err != nil {
+		return err
+	}
+	return s.timerStore.DeleteTimer(timer.ID)
+}
+
+func (s *timerService) CloseStaleTimers() error {
+	now := time.Now()
+	// a timer can only have run for the limit when it started at least that long ago
+	timers, err := s.timerStore.GetTimersStartedBefore(now.Add(-s.maxDuration))
+	if err != nil {
+		return err
+	}
+	for i := range timers {
+		timer := &timers[i]
+		read := elapsed(timer, now)
+		var endedAt time.Time
+		switch {
+		case timer.ResumedAt != nil && read >= s.maxDuration:
+			endedAt = timer.ResumedAt.Add(s.maxDuration - time.Duration(timer.AccumulatedSeconds)*time.Second)
+		case timer.ResumedAt == nil && timer.PausedAt != nil && now.Sub(*timer.PausedAt) >= s.maxDuration:
+			endedAt = *timer.PausedAt
+		default:
+			continue
+		}
+		// a timer that cannot be recorded stays, the next run tries again
+		if _, err := s.record(timer, read, endedAt, 0); errors.Is(err, ErrInvalidInput) {
+			log.Printf("Timers - timer %d of user %d not closed: %v", timer.ID, timer.UserID, err)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *timerService) StartStaleTimerCloser(interval time.Duration) {
+	for {
+		if err := s.CloseStaleTimers(); err != nil {
+			log.Printf("Timers - closing stale timers failed: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
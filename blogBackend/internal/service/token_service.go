@@ -0,0 +1,141 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// personalTokenPrefix marks personal tokens so they are recognisable, e.g. by secret scanners
+const personalTokenPrefix = "blt_"
+
+// maxPersonalTokens caps the tokens one user can hold
+const maxPersonalTokens = 20
+
+// tokenTouchInterval limits how often LastUsedAt is written for a busy token
+const tokenTouchInterval = time.Minute
+
+// ErrInvalidCredentials is returned for any failed token or password check, without saying which part was wrong
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+type TokenService interface {
+	// CreateToken returns the new token's value, it is not stored and can't be shown again.
+	CreateToken(userID int, name string) (string, *model.PersonalToken, error)
+	GetTokens(userID int) ([]model.PersonalToken, error)
+	RevokeToken(tokenID int, userID int) error
+
+	// AuthenticateToken returns the user a personal token belongs to.
+	AuthenticateToken(value string) (uint, error)
+
+	// AuthenticateBasic checks HTTP Basic credentials: the email and either the password or a personal token.
+	AuthenticateBasic(email string, secret string) (uint, error)
+}
+
+type tokenService struct {
+	tokenStore store.TokenStore
+	userStore  store.UserStore
+}
+
+func NewTokenService(tokenStore store.TokenStore, userStore store.UserStore) TokenService {
+	return &tokenService{tokenStore: tokenStore, userStore: userStore}
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *tokenService) CreateToken(userID int, name string) (string, *model.PersonalToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, invalidInput("name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return "", nil, invalidInput("name must be at most 100 characters")
+	}
+	tokens, err := s.tokenStore.GetTokens(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tokens) >= maxPersonalTokens {
+		return "", nil, invalidInput("you can have at most %d personal tokens, revoke one first", maxPersonalTokens)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	value := personalTokenPrefix + hex.EncodeToString(secret)
+	token := &model.PersonalToken{
+		UserID:    uint(userID),
+		Name:      name,
+		TokenHash: hashToken(value),
+		Prefix:    value[:len(personalTokenPrefix)+6],
+	}
+	if err := s.tokenStore.CreateToken(token); err != nil {
+		return "", nil, err
+	}
+	return value, token, nil
+}
+
+func (s *tokenService) GetTokens(userID int) ([]model.PersonalToken, error) {
+	return s.tokenStore.GetTokens(userID)
+}
+
+func (s *tokenService) RevokeToken(tokenID int, userID int) error {
+	return s.tokenStore.DeleteToken(tokenID, userID)
+}
+
+func (s *tokenService) AuthenticateToken(value string) (uint, error) {
+	if !strings.HasPrefix(value, personalTokenPrefix) {
+		return 0, ErrInvalidCredentials
+	}
+	token, err := s.tokenStore.FindTokenByHash(hashToken(value))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	if now := time.Now(); token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval {
+		if err := s.tokenStore.TouchToken(token.ID, now); err != nil {
+			log.Printf("Personal token %d - failed to record use: %v", token.ID, err)
+		}
+	}
+	return token.UserID, nil
+}
+
+func (s *tokenService) AuthenticateBasic(email string, secret string) (uint, error) {
+	user, err := s.userStore.FindUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	// a personal token only counts for the account it belongs to
+	if strings.HasPrefix(secret, personalTokenPrefix) {
+		userID, err := s.AuthenticateToken(secret)
+		if err != nil {
+			return 0, err
+		}
+		if userID != user.ID {
+			return 0, ErrInvalidCredentials
+		}
+		return userID, nil
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(secret)); err != nil {
+		return 0, ErrInvalidCredentials
+	}
+	return user.ID, nil
+}
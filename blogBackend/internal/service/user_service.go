@@ -0,0 +1,172 @@
+package service
+
+import (
+	"project/internal/model"
+	"project/internal/store"
+)
+
+// profileBookLimit caps the books shown on a public profile
+const profileBookLimit = 50
+
+// UserProfile is what another user sees of someone's account
+type UserProfile struct {
+	User           model.PublicUser `json:"user"`
+	FollowerCount  int64            `json:"follower_count"`
+	FollowingCount int64            `json:"following_count"`
+	// IsFollowing is whether the viewer follows this user, always false for anonymous visitors
+	IsFollowing bool            `json:"is_following"`
+	Books       []PublicBookLog `json:"books"`
+}
+
+// UserSettings are the preferences of a user, settings an update omits are left as they are.
+type UserSettings struct {
+	DefaultVisibility *string `json:"defaultVisibility"`
+	Timezone          *string `json:"timezone"`
+	StreakMinMinutes  *int    `json:"streakMinMinutes"`
+	LeaderboardOptOut *bool   `json:"leaderboardOptOut"`
+}
+
+type UserService interface {
+	// GetProfile returns a user's public profile with the books viewerID may see, viewerID 0 is anonymous.
+	GetProfile(userID int, viewerID int) (*UserProfile, error)
+
+	Follow(followerID int, followeeID int) error
+	Unfollow(followerID int, followeeID int) error
+	GetFollowers(userID int, page, pageSize int) ([]model.PublicUser, int64, error)
+	GetFollowing(userID int, page, pageSize int) ([]model.PublicUser, int64, error)
+
+	GetSettings(userID int) (*UserSettings, error)
+	UpdateSettings(userID int, input UserSettings) (*UserSettings, error)
+}
+
+type userService struct {
+	userStore    store.UserStore
+	followStore  store.FollowStore
+	bookLogStore store.BookLogStore
+}
+
+func NewUserService(userStore store.UserStore, followStore store.FollowStore, bookLogStore store.BookLogStore) UserService {
+	return &userService{userStore: userStore, followStore: followStore, bookLogStore: bookLogStore}
+}
+
+func (s *userService) GetProfile(userID int, viewerID int) (*UserProfile, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	_, followers, err := s.followStore.GetFollowers(userID, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	_, following, err := s.followStore.GetFollowing(userID, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	isFollowing := false
+	if viewerID != 0 && viewerID != userID {
+		if isFollowing, err = s.followStore.IsFollowing(viewerID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	books, err := s.bookLogStore.FindVisibleBookLogs(userID, viewerID, profileBookLimit)
+	if err != nil {
+		return nil, err
+	}
+	profile := &UserProfile{
+		User:           user.Public(),
+		FollowerCount:  followers,
+		FollowingCount: following,
+		IsFollowing:    isFollowing,
+		Books:          make([]PublicBookLog, 0, len(books)),
+	}
+	for _, book := range books {
+		book.User = *user
+		profile.Books = append(profile.Books, newPublicBookLog(book))
+	}
+	return profile, nil
+}
+
+func (s *userService) Follow(followerID int, followeeID int) error {
+	if followerID == followeeID {
+		return invalidInput("you cannot follow yourself")
+	}
+	if _, err := s.userStore.FindUserByID(followeeID); err != nil {
+		return err
+	}
+	return s.followStore.Follow(followerID, followeeID)
+}
+
+func (s *userService) Unfollow(followerID int, followeeID int) error {
+	return s.followStore.Unfollow(followerID, followeeID)
+}
+
+func (s *userService) GetFollowers(userID int, page, pageSize int) ([]model.PublicUser, int64, error) {
+	users, total, err := s.followStore.GetFollowers(userID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return publicUsers(users), total, nil
+}
+
+func (s *userService) GetFollowing(userID int, page, pageSize int) ([]model.PublicUser, int64, error) {
+	users, total, err := s.followStore.GetFollowing(userID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return publicUsers(users), total, nil
+}
+
+func publicUsers(users []model.UserLog) []model.PublicUser {
+	result := make([]model.PublicUser, 0, len(users))
+	for _, user := range users {
+		result = append(result, user.Public())
+	}
+	return result
+}
+
+func (s *userService) GetSettings(userID int) (*UserSettings, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserSettings{
+		DefaultVisibility: &user.DefaultVisibility,
+		Timezone:          &user.Timezone,
+		StreakMinMinutes:  &user.StreakMinMinutes,
+		LeaderboardOptOut: &user.LeaderboardOptOut,
+	}, nil
+}
+
+func (s *userService) UpdateSettings(userID int, input UserSettings) (*UserSettings, error) {
+	user, err := s.userStore.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if input.DefaultVisibility != nil {
+		if !isValidVisibility(*input.DefaultVisibility) {
+			return nil, invalidVisibility()
+		}
+		user.DefaultVisibility = *input.DefaultVisibility
+	}
+	if input.Timezone != nil {
+		loc, err := loadTimezone(*input.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		user.Timezone = loc.String()
+	}
+	if input.StreakMinMinutes != nil {
+		if *input.StreakMinMinutes < 1 || *input.StreakMinMinutes > maxSessionMinutes {
+			return nil, invalidInput("streakMinMinutes must be between 1 and %d", maxSessionMinutes)
+		}
+		user.StreakMinMinutes = *input.StreakMinMinutes
+	}
+	if input.LeaderboardOptOut != nil {
+		user.LeaderboardOptOut = *input.LeaderboardOptOut
+	}
+	if err := s.userStore.UpdateSettings(user); err != nil {
+		return nil, err
+	}
+	return s.GetSettings(userID)
+}
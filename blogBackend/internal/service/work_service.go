@@ -0,0 +1,92 @@
+package service
+
+import (
+	"project/internal/model"
+	"project/internal/store"
+	"strings"
+)
+
+type WorkInput struct {
+	Title  string `json:"title" binding:"required"`
+	Author string `json:"author"`
+	// BookIDs are the book logs to group as editions of the work
+	BookIDs []uint `json:"bookIds"`
+}
+
+type WorkService interface {
+	CreateWork(userID int, input WorkInput) (*model.Work, error)
+	GetWorks(userID int) ([]model.Work, error)
+	GetWork(workID int, userID int) (*model.Work, error)
+	DeleteWork(workID int, userID int) error
+
+	AddEdition(workID int, userID int, bookID int) (*model.Work, error)
+	RemoveEdition(workID int, userID int, bookID int) error
+}
+
+type workService struct {
+	workStore    store.WorkStore
+	bookLogStore store.BookLogStore
+}
+
+func NewWorkService(workStore store.WorkStore, bookLogStore store.BookLogStore) WorkService {
+	return &workService{workStore: workStore, bookLogStore: bookLogStore}
+}
+
+func (s *workService) CreateWork(userID int, input WorkInput) (*model.Work, error) {
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		return nil, invalidInput("title cannot be empty")
+	}
+	work := &model.Work{
+		UserID: uint(userID),
+		Title:  truncate(title, 100),
+		Author: truncate(strings.TrimSpace(input.Author), 100),
+	}
+
+	seen := make(map[uint]bool)
+	bookIDs := make([]uint, 0, len(input.BookIDs))
+	for _, id := range input.BookIDs {
+		if !seen[id] {
+			seen[id] = true
+			bookIDs = append(bookIDs, id)
+		}
+	}
+	if err := s.workStore.CreateWork(work, bookIDs); err != nil {
+		return nil, err
+	}
+	return s.workStore.GetWorkByID(int(work.ID), userID)
+}
+
+func (s *workService) GetWorks(userID int) ([]model.Work, error) {
+	return s.workStore.GetWorks(userID)
+}
+
+func (s *workService) GetWork(workID int, userID int) (*model.Work, error) {
+	return s.workStore.GetWorkByID(workID, userID)
+}
+
+func (s *workService) DeleteWork(workID int, userID int) error {
+	return s.workStore.DeleteWork(workID, userID)
+}
+
+func (s *workService) AddEdition(workID int, userID int, bookID int) (*model.Work, error) {
+	work, err := s.workStore.GetWorkByID(workID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bookLogStore.PatchLog(bookID, userID, map[string]interface{}{"work_id": work.ID}); err != nil {
+		return nil, err
+	}
+	return s.workStore.GetWorkByID(workID, userID)
+}
+
+func (s *workService) RemoveEdition(workID int, userID int, bookID int) error {
+	book, err := s.bookLogStore.GetBookByIDAndUserID(bookID, userID)
+	if err != nil {
+		return err
+	}
+	if book.WorkID == nil || *book.WorkID != uint(workID) {
+		return invalidInput("book %d is not an edition of work %d", bookID, workID)
+	}
+	return s.bookLogStore.PatchLog(bookID, userID, map[string]interface{}{"work_id": nil})
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CatalogFilter narrows the books of a catalog feed, empty fields don't filter
+type CatalogFilter struct {
+	Status string
+	Tag    string
+	Author string
+	// Query matches the title, the author or the ISBN
+	Query string
+}
+
+type CatalogStore interface {
+	//this function used to page through a user's book logs matching the filter, ordered by title.
+	FindCatalogBooks(userID int, filter CatalogFilter, page, pageSize int) ([]model.BookLog, int64, error)
+
+	//this function used to count a user's book logs per status.
+	GetStatusCounts(userID int) ([]KeyCount, error)
+
+	//this function used to count a user's book logs per author, books without an author are left out.
+	GetAuthorCounts(userID int) ([]KeyCount, error)
+}
+
+type catalogStore struct {
+	db *gorm.DB
+}
+
+func NewCatalogStore(db *gorm.DB) CatalogStore {
+	return &catalogStore{db: db}
+}
+
+func catalogFilter(filter CatalogFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		if filter.Author != "" {
+			db = db.Where("author = ?", filter.Author)
+		}
+		if filter.Tag != "" {
+			db = db.Where("EXISTS (SELECT 1 FROM book_tags WHERE book_tags.book_log_id = book_logs.id AND book_tags.name = ?)", filter.Tag)
+		}
+		if filter.Query != "" {
+			pattern := "%" + filter.Query + "%"
+			db = db.Where("(title ILIKE ? OR author ILIKE ? OR isbn = ?)", pattern, pattern, filter.Query)
+		}
+		return db
+	}
+}
+
+func (s *catalogStore) FindCatalogBooks(userID int, filter CatalogFilter, page, pageSize int) ([]model.BookLog, int64, error) {
+	var books []model.BookLog
+	var total int64
+	query := s.db.Model(&model.BookLog{}).Where("user_id = ?", userID).Scopes(catalogFilter(filter))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Preload("Tags").Order("title ASC, id ASC").Offset(offset).Limit(pageSize).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return books, total, nil
+}
+
+func (s *catalogStore) GetStatusCounts(userID int) ([]KeyCount, error) {
+	var counts []KeyCount
+	if err := s.db.Model(&model.BookLog{}).Where("user_id = ? AND status <> ''", userID).
+		Select("status AS key, COUNT(*) AS count").
+		Group("status").Order("status ASC").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (s *catalogStore) GetAuthorCounts(userID int) ([]KeyCount, error) {
+	var counts []KeyCount
+	if err := s.db.Model(&model.BookLog{}).Where("user_id = ? AND author <> ''", userID).
+		Select("author AS key, COUNT(*) AS count").
+		Group("author").Order("author ASC").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
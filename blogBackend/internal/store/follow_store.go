@@ -0,0 +1,87 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FollowStore interface {
+	Migrate() error
+
+	//this function used to follow a user, following twice is not an error.
+	Follow(followerID int, followeeID int) error
+	Unfollow(followerID int, followeeID int) error
+	IsFollowing(followerID int, followeeID int) (bool, error)
+
+	//this function used to list who follows the user, newest first.
+	GetFollowers(userID int, page, pageSize int) ([]model.UserLog, int64, error)
+
+	//this function used to list who the user follows, newest first.
+	GetFollowing(userID int, page, pageSize int) ([]model.UserLog, int64, error)
+}
+
+type followStore struct {
+	db *gorm.DB
+}
+
+func NewFollowStore(db *gorm.DB) FollowStore {
+	return &followStore{db: db}
+}
+
+func (s *followStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Follow{})
+}
+
+func (s *followStore) Follow(followerID int, followeeID int) error {
+	follow := &model.Follow{FollowerID: uint(followerID), FolloweeID: uint(followeeID)}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(follow).Error
+}
+
+func (s *followStore) Unfollow(followerID int, followeeID int) error {
+	result := s.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&model.Follow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *followStore) IsFollowing(followerID int, followeeID int) (bool, error) {
+	var count int64
+	if err := s.db.Model(&model.Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *followStore) GetFollowers(userID int, page, pageSize int) ([]model.UserLog, int64, error) {
+	return s.listUsers("follows.follower_id", "follows.followee_id = ?", userID, page, pageSize)
+}
+
+func (s *followStore) GetFollowing(userID int, page, pageSize int) ([]model.UserLog, int64, error) {
+	return s.listUsers("follows.followee_id", "follows.follower_id = ?", userID, page, pageSize)
+}
+
+// listUsers pages through the users on one side of the follows of userID
+func (s *followStore) listUsers(userColumn string, condition string, userID int, page, pageSize int) ([]model.UserLog, int64, error) {
+	var users []model.UserLog
+	var total int64
+
+	if err := s.db.Model(&model.Follow{}).Where(condition, userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err := s.db.Model(&model.UserLog{}).
+		Joins("JOIN follows ON "+userColumn+" = user_logs.id").
+		Where(condition, userID).
+		Order("follows.created_at DESC").Offset(offset).Limit(pageSize).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GoalStore interface {
+	Migrate() error
+
+	//this function used to create the goal for a year or replace its targets.
+	UpsertGoal(goal *model.ReadingGoal) error
+	GetGoalByYear(userID int, year int) (*model.ReadingGoal, error)
+	GetGoals(userID int) ([]model.ReadingGoal, error)
+	DeleteGoal(userID int, year int) error
+}
+
+type goalStore struct {
+	db *gorm.DB
+}
+
+func NewGoalStore(db *gorm.DB) GoalStore {
+	return &goalStore{db: db}
+}
+
+func (s *goalStore) Migrate() error {
+	return s.db.AutoMigrate(&model.ReadingGoal{})
+}
+
+func (s *goalStore) UpsertGoal(goal *model.ReadingGoal) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "year"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target_books", "target_pages", "updated_at"}),
+	}).Create(goal).Error
+}
+
+func (s *goalStore) GetGoalByYear(userID int, year int) (*model.ReadingGoal, error) {
+	var goal model.ReadingGoal
+	if err := s.db.Where("user_id = ? AND year = ?", userID, year).First(&goal).Error; err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (s *goalStore) GetGoals(userID int) ([]model.ReadingGoal, error) {
+	var goals []model.ReadingGoal
+	if err := s.db.Where("user_id = ?", userID).Order("year DESC").Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (s *goalStore) DeleteGoal(userID int, year int) error {
+	// hard delete so the (user, year) unique index is free again
+	result := s.db.Unscoped().Where("user_id = ? AND year = ?", userID, year).Delete(&model.ReadingGoal{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
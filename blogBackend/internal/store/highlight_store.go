@@ -0,0 +1,140 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type HighlightStore interface {
+	Migrate() error
+	CreateHighlight(highlight *model.Highlight) error
+	GetHighlightByIDAndUserID(highlightID int, userID int) (*model.Highlight, error)
+	GetHighlightsByBookID(bookID int, userID int) ([]model.Highlight, error)
+
+	//this function used to update a highlight and replace its tags.
+	UpdateHighlight(highlight *model.Highlight) error
+	DeleteHighlight(highlightID int, userID int) error
+
+	//this function used to search quote and note text across all highlights of a user, optionally filtered by tag.
+	SearchHighlights(userID int, query string, tag string, page, pageSize int) ([]model.Highlight, int64, error)
+	CountHighlights(userID int) (int64, error)
+
+	//this function used to get the highlight at the given offset, ordered by ID. A negative offset picks a random one.
+	GetHighlightAt(userID int, offset int) (*model.Highlight, error)
+}
+
+type highlightStore struct {
+	db *gorm.DB
+}
+
+func NewHighlightStore(db *gorm.DB) HighlightStore {
+	return &highlightStore{db: db}
+}
+
+func (s *highlightStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Highlight{}, &model.HighlightTag{})
+}
+
+func (s *highlightStore) CreateHighlight(highlight *model.Highlight) error {
+	return s.db.Create(highlight).Error
+}
+
+func (s *highlightStore) GetHighlightByIDAndUserID(highlightID int, userID int) (*model.Highlight, error) {
+	var highlight model.Highlight
+	if err := s.db.Preload("Tags").Preload("BookLog").Where("id = ? AND user_id = ?", highlightID, userID).First(&highlight).Error; err != nil {
+		return nil, err
+	}
+	return &highlight, nil
+}
+
+func (s *highlightStore) GetHighlightsByBookID(bookID int, userID int) ([]model.Highlight, error) {
+	var highlights []model.Highlight
+	if err := s.db.Preload("Tags").Where("book_log_id = ? AND user_id = ?", bookID, userID).Order("page ASC NULLS LAST, created_at ASC").Find(&highlights).Error; err != nil {
+		return nil, err
+	}
+	return highlights, nil
+}
+
+func (s *highlightStore) UpdateHighlight(highlight *model.Highlight) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Highlight{}).Where("id = ? AND user_id = ?", highlight.ID, highlight.UserID).
+			Select("page", "location", "quote", "note").Updates(highlight)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("highlight_id = ?", highlight.ID).Delete(&model.HighlightTag{}).Error; err != nil {
+			return err
+		}
+		for i := range highlight.Tags {
+			highlight.Tags[i].ID = 0
+			highlight.Tags[i].HighlightID = highlight.ID
+		}
+		if len(highlight.Tags) > 0 {
+			return tx.Create(&highlight.Tags).Error
+		}
+		return nil
+	})
+}
+
+func (s *highlightStore) DeleteHighlight(highlightID int, userID int) error {
+	result := s.db.Where("id = ? AND user_id = ?", highlightID, userID).Delete(&model.Highlight{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *highlightStore) SearchHighlights(userID int, query string, tag string, page, pageSize int) ([]model.Highlight, int64, error) {
+	var highlights []model.Highlight
+	var total int64
+
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("highlights.user_id = ?", userID)
+		if query != "" {
+			db = db.Where("highlights.quote ILIKE ? OR highlights.note ILIKE ?", "%"+query+"%", "%"+query+"%")
+		}
+		if tag != "" {
+			db = db.Where("EXISTS (SELECT 1 FROM highlight_tags WHERE highlight_tags.highlight_id = highlights.id AND highlight_tags.name = ?)", tag)
+		}
+		return db
+	}
+
+	if err := s.db.Model(&model.Highlight{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := s.db.Scopes(filter).Preload("Tags").Preload("BookLog").Order("highlights.created_at DESC").Offset(offset).Limit(pageSize).Find(&highlights).Error; err != nil {
+		return nil, 0, err
+	}
+	return highlights, total, nil
+}
+
+func (s *highlightStore) CountHighlights(userID int) (int64, error) {
+	var total int64
+	if err := s.db.Model(&model.Highlight{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (s *highlightStore) GetHighlightAt(userID int, offset int) (*model.Highlight, error) {
+	var highlight model.Highlight
+	q := s.db.Preload("Tags").Preload("BookLog").Where("user_id = ?", userID)
+	if offset < 0 {
+		q = q.Order("RANDOM()")
+	} else {
+		q = q.Order("id ASC").Offset(offset)
+	}
+	if err := q.Take(&highlight).Error; err != nil {
+		return nil, err
+	}
+	return &highlight, nil
+}
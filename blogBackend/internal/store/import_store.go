@@ -0,0 +1,53 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobStore interface {
+	Migrate() error
+	CreateJob(job *model.ImportJob) error
+	UpdateJob(job *model.ImportJob) error
+	CreateRowError(rowErr *model.ImportRowError) error
+
+	//this function used to get an import job with its row errors, scoped to the owner.
+	GetJobByIDAndUserID(jobID int, userID int) (*model.ImportJob, error)
+}
+
+type importJobStore struct {
+	db *gorm.DB
+}
+
+func NewImportJobStore(db *gorm.DB) ImportJobStore {
+	return &importJobStore{db: db}
+}
+
+func (s *importJobStore) Migrate() error {
+	return s.db.AutoMigrate(&model.ImportJob{}, &model.ImportRowError{})
+}
+
+func (s *importJobStore) CreateJob(job *model.ImportJob) error {
+	return s.db.Create(job).Error
+}
+
+func (s *importJobStore) UpdateJob(job *model.ImportJob) error {
+	return s.db.Model(&model.ImportJob{}).Where("id = ?", job.ID).Select(
+		"status", "error", "total_rows", "processed_rows", "imported_rows", "skipped_rows", "failed_rows",
+	).Updates(job).Error
+}
+
+func (s *importJobStore) CreateRowError(rowErr *model.ImportRowError) error {
+	return s.db.Create(rowErr).Error
+}
+
+func (s *importJobStore) GetJobByIDAndUserID(jobID int, userID int) (*model.ImportJob, error) {
+	var job model.ImportJob
+	if err := s.db.Preload("RowErrors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line ASC")
+	}).Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
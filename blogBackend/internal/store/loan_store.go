@@ -0,0 +1,157 @@
+package store
+
+import (
+	"project/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoanStore interface {
+	Migrate() error
+	CreateLoan(loan *model.Loan) error
+
+	//this function used to get a loan of the lender with its book and borrower.
+	GetLoanByID(loanID int, userID int) (*model.Loan, error)
+	UpdateLoan(loan *model.Loan) error
+
+	//this function used to mark an outstanding loan as returned.
+	ReturnLoan(loanID int, userID int, returnedAt time.Time) error
+	DeleteLoan(loanID int, userID int) error
+
+	GetOutstandingLoan(bookID int, userID int) (*model.Loan, error)
+	GetOutstandingLoans(userID int) ([]model.Loan, error)
+	GetOverdueLoans(userID int, now time.Time) ([]model.Loan, error)
+
+	//this function used to list loans where the user is the registered borrower, outstanding first.
+	GetBorrowedLoans(userID int) ([]model.Loan, error)
+	GetBookLoans(bookID int, userID int) ([]model.Loan, error)
+
+	//this function used to find overdue loans across all users that were not notified yet.
+	FindOverdueToNotify(now time.Time, limit int) ([]model.Loan, error)
+	MarkOverdueNotified(loanID uint, at time.Time) error
+}
+
+type loanStore struct {
+	db *gorm.DB
+}
+
+func NewLoanStore(db *gorm.DB) LoanStore {
+	return &loanStore{db: db}
+}
+
+func (s *loanStore) Migrate() error {
+	if err := s.db.AutoMigrate(&model.Loan{}); err != nil {
+		return err
+	}
+	// loans of book logs deleted or merged away before their loans went with them
+	return s.db.Where("book_log_id IN (SELECT id FROM book_logs WHERE deleted_at IS NOT NULL)").Delete(&model.Loan{}).Error
+}
+
+func (s *loanStore) CreateLoan(loan *model.Loan) error {
+	return s.db.Create(loan).Error
+}
+
+func preloadLoan(db *gorm.DB) *gorm.DB {
+	return db.Preload("BookLog").Preload("BorrowerUser")
+}
+
+func (s *loanStore) GetLoanByID(loanID int, userID int) (*model.Loan, error) {
+	var loan model.Loan
+	if err := s.db.Scopes(preloadLoan).Where("id = ? AND user_id = ?", loanID, userID).First(&loan).Error; err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (s *loanStore) UpdateLoan(loan *model.Loan) error {
+	result := s.db.Model(&model.Loan{}).Where("id = ? AND user_id = ?", loan.ID, loan.UserID).
+		Select("borrower_user_id", "borrower_name", "lent_at", "due_at", "overdue_notified_at").Updates(loan)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *loanStore) ReturnLoan(loanID int, userID int, returnedAt time.Time) error {
+	result := s.db.Model(&model.Loan{}).Where("id = ? AND user_id = ? AND returned_at IS NULL", loanID, userID).
+		Update("returned_at", returnedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *loanStore) DeleteLoan(loanID int, userID int) error {
+	result := s.db.Where("id = ? AND user_id = ?", loanID, userID).Delete(&model.Loan{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *loanStore) GetOutstandingLoan(bookID int, userID int) (*model.Loan, error) {
+	var loan model.Loan
+	if err := s.db.Where("book_log_id = ? AND user_id = ? AND returned_at IS NULL", bookID, userID).First(&loan).Error; err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (s *loanStore) GetOutstandingLoans(userID int) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := s.db.Scopes(preloadLoan).Where("user_id = ? AND returned_at IS NULL", userID).
+		Order("due_at ASC NULLS LAST, lent_at ASC").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (s *loanStore) GetOverdueLoans(userID int, now time.Time) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := s.db.Scopes(preloadLoan).Where("user_id = ? AND returned_at IS NULL AND due_at < ?", userID, now).
+		Order("due_at ASC").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (s *loanStore) GetBorrowedLoans(userID int) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := s.db.Scopes(preloadLoan).Preload("User").Where("borrower_user_id = ?", userID).
+		Order("returned_at IS NOT NULL, due_at ASC NULLS LAST, lent_at DESC").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (s *loanStore) GetBookLoans(bookID int, userID int) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := s.db.Preload("BorrowerUser").Where("book_log_id = ? AND user_id = ?", bookID, userID).
+		Order("lent_at DESC").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (s *loanStore) FindOverdueToNotify(now time.Time, limit int) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := s.db.Preload("BookLog").Where("returned_at IS NULL AND due_at < ? AND overdue_notified_at IS NULL", now).
+		Order("due_at ASC").Limit(limit).Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (s *loanStore) MarkOverdueNotified(loanID uint, at time.Time) error {
+	return s.db.Model(&model.Loan{}).Where("id = ?", loanID).Update("overdue_notified_at", at).Error
+}
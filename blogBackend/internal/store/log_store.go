@@ -1,11 +1,40 @@
 package store
 
 import (
+	"bytes"
+	"encoding/json"
 	"project/internal/model"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Batch actions on book logs
+const (
+	BatchSetStatus  = "set_status"
+	BatchSetRating  = "set_rating"
+	BatchAddTags    = "add_tags"
+	BatchRemoveTags = "remove_tags"
+	BatchDelete     = "delete"
+)
+
+// BookLogBatchOp is one action applied to a set of book logs, only the fields of its action are used
+type BookLogBatchOp struct {
+	Action string
+	IDs    []uint
+	Status string
+	Rating *int
+	Tags   []string
+}
+
+// TagCount is a shelf name with the number of books on it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
 type BookLogStore interface {
 	Create(userid int, book *model.BookLog) error
 	Migrate() error
@@ -14,7 +43,48 @@ type BookLogStore interface {
 	//this function used to get the details of a book log by its ID and user ID.
 	GetBookByIDAndUserID(bookID int, userID int) (*model.BookLog, error)
 	UpdateLog(log *model.BookLog) error
-	SearchBookByTitleOrAuthor(query string) ([]model.BookLog, error)
+
+	//this function used to update only the given columns of a book log, zero values included.
+	PatchLog(bookID int, userID int, fields map[string]interface{}) error
+	//this function used to search the book logs the viewer may see, viewerID 0 is an anonymous visitor.
+	SearchBookByTitleOrAuthor(query string, viewerID int) ([]model.BookLog, error)
+
+	//this function used to list a user's book logs as the viewer may see them, most recently updated first.
+	FindVisibleBookLogs(ownerID int, viewerID int, limit int) ([]model.BookLog, error)
+
+	//this function used to walk all book logs of a user in batches, e.g. for exports.
+	IterateBookLogs(userID int, batchSize int, fn func(books []model.BookLog) error) error
+
+	//this function used to walk every user's book logs in batches, for jobs working across the whole library.
+	//private book logs are included, callers keep them from showing up for other users.
+	IterateAllBookLogs(batchSize int, fn func(books []model.BookLog) error) error
+
+	//this function used to count books marked as read in [from, to) and the pages they add up to.
+	GetFinishedTotals(userID int, from, to time.Time) (books int64, pages int64, err error)
+
+	FindBookLogsByTag(userID int, tag string) ([]model.BookLog, error)
+	GetTags(userID int) ([]TagCount, error)
+
+	//this function used to filter ids down to the book logs owned by the user.
+	FindOwnedBookIDs(userID int, ids []uint) ([]uint, error)
+
+	//this function used to apply all operations in one transaction, nothing is written if one fails.
+	ApplyBatch(userID int, ops []BookLogBatchOp) error
+
+	//this function used to merge a duplicate into the survivor: the survivor gets fields, everything
+	//attached to the duplicate is moved over and the duplicate is deleted, all in one transaction.
+	//gorm.ErrDuplicatedKey when both books are lent out, a book log has at most one outstanding loan.
+	MergeBookLogs(userID int, survivorID uint, fields map[string]interface{}, duplicateID uint) error
+
+	//this function used to take a queued want-to-read book out of the up next queue and mark it as reading, in one transaction.
+	StartQueuedBook(userID int, bookID uint) error
+
+	//this function used to list the revisions of a book log, newest first.
+	GetRevisions(bookID int, userID int) ([]model.BookLogRevision, error)
+	GetRevision(revisionID int, bookID int, userID int) (*model.BookLogRevision, error)
+
+	//this function used to write fields and optionally replace the tags (nil keeps them), recorded as a revert of revisionID.
+	RevertBookLog(userID int, bookID int, revisionID uint, fields map[string]interface{}, tags []string) error
 }
 
 type bookLogStore struct {
@@ -26,7 +96,26 @@ func NewBookLogStore(db *gorm.DB) BookLogStore {
 }
 
 func (s *bookLogStore) Migrate() error {
-	return s.db.AutoMigrate(&model.BookLog{})
+	addingFinishedAt := !s.db.Migrator().HasColumn(&model.BookLog{}, "FinishedAt")
+	addingVisibility := !s.db.Migrator().HasColumn(&model.BookLog{}, "Visibility")
+	if err := s.db.AutoMigrate(&model.BookLog{}, &model.BookTag{}, &model.BookLogRevision{}); err != nil {
+		return err
+	}
+	// books read before finished_at existed count as finished when they were last updated,
+	// the closest the old rows have to it
+	if addingFinishedAt {
+		if err := s.db.Model(&model.BookLog{}).Unscoped().Where("status = ? AND finished_at IS NULL", model.BookStatusRead).
+			UpdateColumn("finished_at", gorm.Expr("updated_at")).Error; err != nil {
+			return err
+		}
+	}
+	// book logs from before visibility existed become private, except the ones with a published
+	// review, those stay in the review feed. Only done once so later choices aren't overridden.
+	if addingVisibility && s.db.Migrator().HasTable(&model.Review{}) {
+		return s.db.Model(&model.BookLog{}).Unscoped().Where("id IN (SELECT book_log_id FROM reviews)").
+			UpdateColumn("visibility", model.VisibilityPublic).Error
+	}
+	return nil
 }
 
 func (s *bookLogStore) Create(userid int, book *model.BookLog) error {
@@ -35,6 +124,7 @@ func (s *bookLogStore) Create(userid int, book *model.BookLog) error {
 		Title:       book.Title,
 		Author:      book.Author,
 		CoverUrl:    book.CoverUrl,
+		PageCount:   book.PageCount,
 		Description: book.Description,
 		PublishedAt: book.PublishedAt,
 		ISBN:        book.ISBN,
@@ -44,8 +134,22 @@ func (s *bookLogStore) Create(userid int, book *model.BookLog) error {
 		MyRating:    book.MyRating,
 		MyComment:   book.MyComment,
 		Status:      book.Status,
+		FinishedAt:  book.FinishedAt,
+		Visibility:  book.Visibility,
+	}
+	if newbook.Visibility == "" {
+		var user model.UserLog
+		if err := s.db.Select("default_visibility").Where("id = ?", userid).First(&user).Error; err != nil {
+			return err
+		}
+		newbook.Visibility = user.DefaultVisibility
+	}
+	if err := s.db.Create(newbook).Error; err != nil {
+		return err
 	}
-	return s.db.Create(newbook).Error
+	book.ID = newbook.ID
+	book.Visibility = newbook.Visibility
+	return nil
 }
 
 func (s *bookLogStore) FindBookLogByStatus(userID int, status string) ([]model.BookLog, error) {
@@ -57,7 +161,7 @@ func (s *bookLogStore) FindBookLogByStatus(userID int, status string) ([]model.B
 		query = query.Where("status = ?", status)
 	}
 
-	if err := query.Find(&books).Error; err != nil {
+	if err := query.Preload("Tags").Find(&books).Error; err != nil {
 		return nil, err
 	}
 	return books, nil // Assuming you want the first book log found
@@ -65,29 +169,429 @@ func (s *bookLogStore) FindBookLogByStatus(userID int, status string) ([]model.B
 
 func (s *bookLogStore) GetBookByIDAndUserID(bookID int, userID int) (*model.BookLog, error) {
 	var book model.BookLog
-	if err := s.db.Where("id = ? AND user_id = ?", bookID, userID).First(&book).Error; err != nil {
+	if err := s.db.Preload("Tags").Where("id = ? AND user_id = ?", bookID, userID).First(&book).Error; err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
 func (s *bookLogStore) UpdateLog(log *model.BookLog) error {
-	result := s.db.Model(&model.BookLog{}).Where("id = ? AND user_id = ?", log.ID, log.UserID).Updates(log)
+	return s.withRevisions(int(log.UserID), []uint{log.ID}, nil, func(tx *gorm.DB) error {
+		result := tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ?", log.ID, log.UserID).Omit(clause.Associations).Updates(log)
+
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if result.Error != nil {
-		return result.Error
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (s *bookLogStore) PatchLog(bookID int, userID int, fields map[string]interface{}) error {
+	return s.withRevisions(userID, []uint{uint(bookID)}, nil, func(tx *gorm.DB) error {
+		result := tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ?", bookID, userID).Updates(fields)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// VisibleBookLogs limits book_logs to the rows viewerID may see: their own, public ones and
+// followers-only ones of users they follow who follow them back, anyone can follow anyone so a
+// one-sided follow is not enough. viewerID 0 only sees public book logs.
+func VisibleBookLogs(viewerID int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db.Where("book_logs.visibility = ?", model.VisibilityPublic)
+		}
+		return db.Where(`(book_logs.user_id = ? OR book_logs.visibility = ? OR (book_logs.visibility = ? AND EXISTS (
+			SELECT 1 FROM follows WHERE follows.follower_id = ? AND follows.followee_id = book_logs.user_id) AND EXISTS (
+			SELECT 1 FROM follows WHERE follows.follower_id = book_logs.user_id AND follows.followee_id = ?)))`,
+			viewerID, model.VisibilityPublic, model.VisibilityFollowers, viewerID, viewerID)
 	}
+}
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+func (s *bookLogStore) SearchBookByTitleOrAuthor(query string, viewerID int) ([]model.BookLog, error) {
+	var books []model.BookLog
+	if err := s.db.Preload("User").Scopes(VisibleBookLogs(viewerID)).
+		Where("(title LIKE ? OR author LIKE ?)", "%"+query+"%", "%"+query+"%").Limit(20).Find(&books).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return books, nil
+}
+
+func (s *bookLogStore) FindVisibleBookLogs(ownerID int, viewerID int, limit int) ([]model.BookLog, error) {
+	var books []model.BookLog
+	if err := s.db.Scopes(VisibleBookLogs(viewerID)).Where("user_id = ?", ownerID).
+		Order("updated_at DESC").Limit(limit).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (s *bookLogStore) IterateBookLogs(userID int, batchSize int, fn func(books []model.BookLog) error) error {
+	var books []model.BookLog
+	return s.db.Preload("Tags").Where("user_id = ?", userID).Order("id ASC").FindInBatches(&books, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(books)
+	}).Error
+}
+
+func (s *bookLogStore) IterateAllBookLogs(batchSize int, fn func(books []model.BookLog) error) error {
+	var books []model.BookLog
+	return s.db.Order("id ASC").FindInBatches(&books, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(books)
+	}).Error
+}
+
+func (s *bookLogStore) GetFinishedTotals(userID int, from, to time.Time) (int64, int64, error) {
+	var totals struct {
+		Books int64
+		Pages int64
+	}
+	err := s.db.Model(&model.BookLog{}).
+		Select("COUNT(*) AS books, COALESCE(SUM(page_count), 0) AS pages").
+		Where("user_id = ? AND status = ? AND finished_at >= ? AND finished_at < ?", userID, model.BookStatusRead, from, to).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return totals.Books, totals.Pages, nil
 }
 
-func (s *bookLogStore) SearchBookByTitleOrAuthor(query string) ([]model.BookLog, error) {
+func (s *bookLogStore) FindBookLogsByTag(userID int, tag string) ([]model.BookLog, error) {
 	var books []model.BookLog
-	if err := s.db.Preload("User").Where("title LIKE ? OR author LIKE ?", "%"+query+"%", "%"+query+"%").Limit(20).Find(&books).Error; err != nil {
+	if err := s.db.Preload("Tags").Where("user_id = ?", userID).
+		Where("EXISTS (SELECT 1 FROM book_tags WHERE book_tags.book_log_id = book_logs.id AND book_tags.name = ?)", tag).
+		Find(&books).Error; err != nil {
 		return nil, err
 	}
 	return books, nil
 }
+
+func (s *bookLogStore) GetTags(userID int) ([]TagCount, error) {
+	var tags []TagCount
+	if err := s.db.Model(&model.BookTag{}).
+		Select("book_tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN book_logs ON book_logs.id = book_tags.book_log_id AND book_logs.deleted_at IS NULL").
+		Where("book_logs.user_id = ?", userID).
+		Group("book_tags.name").Order("book_tags.name ASC").
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *bookLogStore) FindOwnedBookIDs(userID int, ids []uint) ([]uint, error) {
+	var owned []uint
+	if len(ids) == 0 {
+		return owned, nil
+	}
+	if err := s.db.Model(&model.BookLog{}).Where("user_id = ? AND id IN ?", userID, ids).Pluck("id", &owned).Error; err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
+func (s *bookLogStore) ApplyBatch(userID int, ops []BookLogBatchOp) error {
+	var ids []uint
+	for _, op := range ops {
+		ids = append(ids, op.IDs...)
+	}
+	return s.withRevisions(userID, ids, nil, func(tx *gorm.DB) error {
+		for _, op := range ops {
+			// the caller checks ownership, updates are scoped to the user again as a safeguard
+			books := tx.Model(&model.BookLog{}).Where("user_id = ? AND id IN ?", userID, op.IDs)
+
+			var err error
+			switch op.Action {
+			case BatchSetStatus:
+				fields := map[string]interface{}{"status": op.Status}
+				if op.Status == model.BookStatusRead {
+					fields["finished_at"] = gorm.Expr("COALESCE(finished_at, ?)", time.Now())
+				}
+				err = books.Updates(fields).Error
+			case BatchSetRating:
+				err = books.Update("my_rating", op.Rating).Error
+			case BatchDelete:
+				// the loans of a deleted book go with it, they would stay outstanding against nothing
+				if err = tx.Where("user_id = ? AND book_log_id IN ?", userID, op.IDs).Delete(&model.Loan{}).Error; err == nil {
+					err = tx.Where("user_id = ? AND id IN ?", userID, op.IDs).Delete(&model.BookLog{}).Error
+				}
+			case BatchAddTags:
+				var tags []model.BookTag
+				for _, id := range op.IDs {
+					for _, name := range op.Tags {
+						tags = append(tags, model.BookTag{BookLogID: id, Name: name})
+					}
+				}
+				if len(tags) > 0 {
+					err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error
+				}
+			case BatchRemoveTags:
+				err = tx.Where("book_log_id IN (?) AND name IN ?",
+					tx.Model(&model.BookLog{}).Select("id").Where("user_id = ? AND id IN ?", userID, op.IDs), op.Tags).
+					Delete(&model.BookTag{}).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *bookLogStore) MergeBookLogs(userID int, survivorID uint, fields map[string]interface{}, duplicateID uint) error {
+	return s.withRevisions(userID, []uint{survivorID}, nil, func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.BookLog{}).Where("user_id = ? AND id IN ?", userID, []uint{survivorID, duplicateID}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if len(fields) > 0 {
+			if err := tx.Model(&model.BookLog{}).Where("id = ?", survivorID).Updates(fields).Error; err != nil {
+				return err
+			}
+		}
+
+		// highlights and reading sessions simply move over
+		if err := tx.Model(&model.Highlight{}).Where("book_log_id = ?", duplicateID).Update("book_log_id", survivorID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Read{}).Where("book_log_id = ?", duplicateID).Update("book_log_id", survivorID).Error; err != nil {
+			return err
+		}
+
+		// tags are unioned, the unique (book, name) index drops the ones the survivor already has
+		if err := tx.Exec(`INSERT INTO book_tags (book_log_id, name)
+			SELECT ?, name FROM book_tags WHERE book_log_id = ?
+			ON CONFLICT DO NOTHING`, survivorID, duplicateID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("book_log_id = ?", duplicateID).Delete(&model.BookTag{}).Error; err != nil {
+			return err
+		}
+
+		// a published review moves only when the survivor has none, one book log has at most one review
+		var survivorReviews int64
+		if err := tx.Model(&model.Review{}).Where("book_log_id = ?", survivorID).Count(&survivorReviews).Error; err != nil {
+			return err
+		}
+		if survivorReviews == 0 {
+			if err := tx.Model(&model.Review{}).Where("book_log_id = ?", duplicateID).Update("book_log_id", survivorID).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Where("book_log_id = ?", duplicateID).Delete(&model.Review{}).Error; err != nil {
+			return err
+		}
+
+		// loans move over, unless both books are lent out and the survivor would have two outstanding loans
+		var outstanding int64
+		if err := tx.Model(&model.Loan{}).Where("book_log_id IN ? AND returned_at IS NULL", []uint{survivorID, duplicateID}).
+			Distinct("book_log_id").Count(&outstanding).Error; err != nil {
+			return err
+		}
+		if outstanding == 2 {
+			return gorm.ErrDuplicatedKey
+		}
+		if err := tx.Model(&model.Loan{}).Where("book_log_id = ?", duplicateID).Update("book_log_id", survivorID).Error; err != nil {
+			return err
+		}
+
+		// the survivor keeps its place in the up next queue, otherwise it takes the duplicate's
+		var survivorQueued int64
+		if err := tx.Model(&model.QueueEntry{}).Where("book_log_id = ?", survivorID).Count(&survivorQueued).Error; err != nil {
+			return err
+		}
+		if survivorQueued == 0 {
+			if err := tx.Model(&model.QueueEntry{}).Where("book_log_id = ?", duplicateID).Update("book_log_id", survivorID).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Where("book_log_id = ?", duplicateID).Delete(&model.QueueEntry{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("id = ? AND user_id = ?", duplicateID, userID).Delete(&model.BookLog{}).Error
+	})
+}
+
+func (s *bookLogStore) StartQueuedBook(userID int, bookID uint) error {
+	return s.withRevisions(userID, []uint{bookID}, nil, func(tx *gorm.DB) error {
+		// a concurrent pop of the same entry finds nothing left to delete
+		result := tx.Where("user_id = ? AND book_log_id = ?", userID, bookID).Delete(&model.QueueEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		result = tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ? AND status = ?", bookID, userID, model.BookStatusWantToRead).
+			Update("status", model.BookStatusReading)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (s *bookLogStore) GetRevisions(bookID int, userID int) ([]model.BookLogRevision, error) {
+	var revisions []model.BookLogRevision
+	if err := s.db.Where("book_log_id = ? AND user_id = ?", bookID, userID).Order("id DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (s *bookLogStore) GetRevision(revisionID int, bookID int, userID int) (*model.BookLogRevision, error) {
+	var revision model.BookLogRevision
+	if err := s.db.Where("id = ? AND book_log_id = ? AND user_id = ?", revisionID, bookID, userID).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (s *bookLogStore) RevertBookLog(userID int, bookID int, revisionID uint, fields map[string]interface{}, tags []string) error {
+	return s.withRevisions(userID, []uint{uint(bookID)}, &revisionID, func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ?", bookID, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(fields) > 0 {
+			if err := tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ?", bookID, userID).Updates(fields).Error; err != nil {
+				return err
+			}
+		}
+		if tags == nil {
+			return nil
+		}
+		if err := tx.Where("book_log_id = ?", bookID).Delete(&model.BookTag{}).Error; err != nil {
+			return err
+		}
+		for _, name := range tags {
+			if err := tx.Create(&model.BookTag{BookLogID: uint(bookID), Name: name}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withRevisions runs fn in a transaction and appends a revision for every book log in ids that fn changed.
+func (s *bookLogStore) withRevisions(userID int, ids []uint, revertOf *uint, fn func(tx *gorm.DB) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		before, err := snapshotBookLogs(tx, userID, ids)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		after, err := snapshotBookLogs(tx, userID, ids)
+		if err != nil {
+			return err
+		}
+
+		for id, old := range before {
+			// deleted book logs have no new state, their history stays as it was
+			current, ok := after[id]
+			if !ok {
+				continue
+			}
+			changes, err := diffBookLogs(old, current)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			revision := &model.BookLogRevision{BookLogID: id, UserID: uint(userID), Changes: changes, RevertOf: revertOf}
+			if err := tx.Create(revision).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func snapshotBookLogs(tx *gorm.DB, userID int, ids []uint) (map[uint]model.BookLog, error) {
+	var books []model.BookLog
+	if err := tx.Preload("Tags").Where("user_id = ? AND id IN ?", userID, ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	snapshot := make(map[uint]model.BookLog, len(books))
+	for _, book := range books {
+		snapshot[book.ID] = book
+	}
+	return snapshot, nil
+}
+
+// RevisionFields are the book log values tracked in the revision log, keyed by column name.
+// "tags" is the sorted list of shelf names.
+func RevisionFields(book model.BookLog) map[string]interface{} {
+	tags := make([]string, 0, len(book.Tags))
+	for _, tag := range book.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+	return map[string]interface{}{
+		"title":        book.Title,
+		"author":       book.Author,
+		"description":  book.Description,
+		"published_at": book.PublishedAt,
+		"isbn":         book.ISBN,
+		"category":     book.Category,
+		"rating":       book.Rating,
+		"review":       book.Review,
+		"cover_url":    book.CoverUrl,
+		"page_count":   book.PageCount,
+		"my_rating":    book.MyRating,
+		"my_comment":   book.MyComment,
+		"status":       book.Status,
+		"visibility":   book.Visibility,
+		"finished_at":  book.FinishedAt,
+		"tags":         tags,
+	}
+}
+
+func diffBookLogs(before, after model.BookLog) ([]model.FieldChange, error) {
+	old, current := RevisionFields(before), RevisionFields(after)
+	fields := make([]string, 0, len(old))
+	for field := range old {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var changes []model.FieldChange
+	for _, field := range fields {
+		oldJSON, err := json.Marshal(old[field])
+		if err != nil {
+			return nil, err
+		}
+		newJSON, err := json.Marshal(current[field])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(oldJSON, newJSON) {
+			changes = append(changes, model.FieldChange{Field: field, Old: oldJSON, New: newJSON})
+		}
+	}
+	return changes, nil
+}
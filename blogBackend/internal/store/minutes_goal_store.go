@@ -0,0 +1,57 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MinutesGoalStore interface {
+	Migrate() error
+
+	//this function used to set the goal starting on a day, a goal starting on the same day is replaced.
+	UpsertMinutesGoal(goal *model.MinutesGoal) error
+
+	//this function used to list the minutes goals of the user, earliest effective date first.
+	GetMinutesGoals(userID int) ([]model.MinutesGoal, error)
+	DeleteMinutesGoal(goalID int, userID int) error
+}
+
+type minutesGoalStore struct {
+	db *gorm.DB
+}
+
+func NewMinutesGoalStore(db *gorm.DB) MinutesGoalStore {
+	return &minutesGoalStore{db: db}
+}
+
+func (s *minutesGoalStore) Migrate() error {
+	return s.db.AutoMigrate(&model.MinutesGoal{})
+}
+
+func (s *minutesGoalStore) UpsertMinutesGoal(goal *model.MinutesGoal) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "effective_from"}},
+		DoUpdates: clause.AssignmentColumns([]string{"period", "target_minutes", "updated_at"}),
+	}).Create(goal).Error
+}
+
+func (s *minutesGoalStore) GetMinutesGoals(userID int) ([]model.MinutesGoal, error) {
+	var goals []model.MinutesGoal
+	if err := s.db.Where("user_id = ?", userID).Order("effective_from ASC").Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (s *minutesGoalStore) DeleteMinutesGoal(goalID int, userID int) error {
+	result := s.db.Where("id = ? AND user_id = ?", goalID, userID).Delete(&model.MinutesGoal{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"project/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationStore interface {
+	Migrate() error
+	CreateNotification(notification *model.Notification) error
+
+	//this function used to page through a user's notifications, newest first.
+	GetNotifications(userID int, unreadOnly bool, page, pageSize int) ([]model.Notification, int64, error)
+	CountUnread(userID int) (int64, error)
+	MarkRead(notificationID int, userID int) error
+	MarkAllRead(userID int) error
+}
+
+type notificationStore struct {
+	db *gorm.DB
+}
+
+func NewNotificationStore(db *gorm.DB) NotificationStore {
+	return &notificationStore{db: db}
+}
+
+func (s *notificationStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Notification{})
+}
+
+func (s *notificationStore) CreateNotification(notification *model.Notification) error {
+	return s.db.Create(notification).Error
+}
+
+func unreadOnly(unread bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if unread {
+			return db.Where("read_at IS NULL")
+		}
+		return db
+	}
+}
+
+func (s *notificationStore) GetNotifications(userID int, unread bool, page, pageSize int) ([]model.Notification, int64, error) {
+	var notifications []model.Notification
+	var total int64
+
+	if err := s.db.Model(&model.Notification{}).Where("user_id = ?", userID).Scopes(unreadOnly(unread)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := s.db.Where("user_id = ?", userID).Scopes(unreadOnly(unread)).
+		Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (s *notificationStore) CountUnread(userID int) (int64, error) {
+	var count int64
+	err := s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (s *notificationStore) MarkRead(notificationID int, userID int) error {
+	var notification model.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return s.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+func (s *notificationStore) MarkAllRead(userID int) error {
+	return s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now()).Error
+}
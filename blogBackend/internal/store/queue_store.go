@@ -0,0 +1,89 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QueueStore interface {
+	Migrate() error
+
+	//this function used to list the user's queue in order, books that are no longer want-to-read are left out.
+	GetQueue(userID int) ([]model.QueueEntry, error)
+
+	//this function used to put a book into the queue, a book already queued just gets the new sort key.
+	UpsertEntry(entry *model.QueueEntry) error
+	UpdateSortKey(userID int, bookID uint, sortKey string) error
+
+	//this function used to rewrite the sort keys of several entries at once, keyed by book log id.
+	UpdateSortKeys(userID int, sortKeys map[uint]string) error
+	RemoveEntry(userID int, bookID int) error
+}
+
+type queueStore struct {
+	db *gorm.DB
+}
+
+func NewQueueStore(db *gorm.DB) QueueStore {
+	return &queueStore{db: db}
+}
+
+func (s *queueStore) Migrate() error {
+	return s.db.AutoMigrate(&model.QueueEntry{})
+}
+
+func (s *queueStore) GetQueue(userID int) ([]model.QueueEntry, error) {
+	var entries []model.QueueEntry
+	// COLLATE "C" compares the keys byte by byte whatever the database locale is
+	if err := s.db.Preload("BookLog").
+		Joins("JOIN book_logs ON book_logs.id = queue_entries.book_log_id AND book_logs.deleted_at IS NULL").
+		Where("queue_entries.user_id = ? AND book_logs.status = ?", userID, model.BookStatusWantToRead).
+		Order(`queue_entries.sort_key COLLATE "C", queue_entries.id`).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *queueStore) UpsertEntry(entry *model.QueueEntry) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_log_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sort_key"}),
+	}).Create(entry).Error
+}
+
+func (s *queueStore) UpdateSortKey(userID int, bookID uint, sortKey string) error {
+	result := s.db.Model(&model.QueueEntry{}).Where("user_id = ? AND book_log_id = ?", userID, bookID).Update("sort_key", sortKey)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *queueStore) UpdateSortKeys(userID int, sortKeys map[uint]string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for bookID, sortKey := range sortKeys {
+			if err := tx.Model(&model.QueueEntry{}).Where("user_id = ? AND book_log_id = ?", userID, bookID).
+				Update("sort_key", sortKey).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *queueStore) RemoveEntry(userID int, bookID int) error {
+	result := s.db.Where("user_id = ? AND book_log_id = ?", userID, bookID).Delete(&model.QueueEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
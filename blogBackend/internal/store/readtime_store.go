@@ -3,15 +3,72 @@ package store
 import (
 	"project/internal/model"
 	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ReadFilter narrows a listing of reading sessions, nil fields don't filter
+type ReadFilter struct {
+	BookID *uint
+	// From and To limit StartedAt to [From, To)
+	From *time.Time
+	To   *time.Time
+}
+
+// ReadBucket is the reading done in a day, week or month, Start is its first day as YYYY-MM-DD
+type ReadBucket struct {
+	Start    string `json:"start"`
+	Minutes  int    `json:"minutes"`
+	Sessions int    `json:"sessions"`
+}
+
+// ReadStreak is a run of consecutive reading days, Start and End are YYYY-MM-DD and inclusive
+type ReadStreak struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+// ReadCursor is the position of a client in the change feed of its sessions
+type ReadCursor struct {
+	UpdatedAt time.Time
+	ID        uint
+}
+
 type ReadTimeStore interface {
+	//this function used to save a new session, gorm.ErrDuplicatedKey when its client id is already stored.
 	CreateReadTime(userID int, read *model.Read) error
 	Migrate() error
-	// GetReadTimeByUserID(userID int) (*model.Read, error)
-	// UpdateReadTime(userID int, read *model.Read) error
+
+	//this function used to save several sessions in one transaction, reporting per session whether it was new.
+	CreateReadTimes(reads []*model.Read) ([]bool, error)
+
+	//this function used to get the sessions of the user with the given client ids, deleted ones included.
+	GetReadTimesByClientIDs(userID int, clientIDs []string) ([]model.Read, error)
+
+	//this function used to list the sessions changed or deleted after the cursor and not after until, oldest change first.
+	GetReadTimeChanges(userID int, after *ReadCursor, until time.Time, limit int) ([]model.Read, error)
+
+	//this function used to get a reading session of the user with its book.
+	GetReadTimeByID(readID int, userID int) (*model.Read, error)
+	UpdateReadTime(read *model.Read) error
+	DeleteReadTime(readID int, userID int) error
+
+	//this function used to page through the user's reading sessions, latest first.
+	GetReadTimes(userID int, filter ReadFilter, page, pageSize int) ([]model.Read, int64, error)
 	GetWeeklyReadTime(userID int) ([]model.Read, error)
+	GetTotalReadTime(userID int) (int, error)
+
+	//this function used to total the minutes read per day, week or month in the timezone tz over [from, to).
+	//every bucket in the range is returned, also the ones without reading.
+	GetReadTimeBuckets(userID int, unit string, tz *time.Location, from, to time.Time) ([]ReadBucket, error)
+
+	//this function used to find every streak of days in the timezone tz with at least minMinutes of reading, oldest first.
+	GetReadStreaks(userID int, tz *time.Location, minMinutes int) ([]ReadStreak, error)
+
+	//this function used to sum the minutes read per book, keyed by book log id.
+	GetBookReadTotals(userID int) (map[uint]int, error)
 }
 
 type readTimeStore struct {
@@ -23,22 +80,217 @@ func NewReadTimeStore(db *gorm.DB) ReadTimeStore {
 }
 
 func (s *readTimeStore) Migrate() error {
-	return s.db.AutoMigrate(&model.Read{})
+	if err := s.db.AutoMigrate(&model.Read{}); err != nil {
+		return err
+	}
+	// sessions logged before started_at existed happened when they were saved
+	if err := s.db.Model(&model.Read{}).Unscoped().Where("started_at IS NULL").
+		UpdateColumn("started_at", gorm.Expr("created_at")).Error; err != nil {
+		return err
+	}
+	// the change feed walks a user's sessions by updated_at, deleted ones included
+	return s.db.Exec("CREATE INDEX IF NOT EXISTS idx_reads_user_updated_at ON reads (user_id, updated_at, id)").Error
 }
 
 func (s *readTimeStore) CreateReadTime(userID int, read *model.Read) error {
 	readtime := &model.Read{
-		UserID: uint(userID), // 确保类型匹配
-		Time:   read.Time,
+		UserID:    uint(userID), // 确保类型匹配
+		Time:      read.Time,
+		BookLogID: read.BookLogID,
+		StartedAt: read.StartedAt,
+		EndedAt:   read.EndedAt,
+		Pages:     read.Pages,
+		ClientID:  read.ClientID,
+	}
+	created, err := createRead(s.db, readtime)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return gorm.ErrDuplicatedKey
+	}
+	read.ID = readtime.ID
+	return nil
+}
+
+// createRead inserts a session unless its client id is already stored
+func createRead(db *gorm.DB, read *model.Read) (bool, error) {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoNothing: true,
+	}).Create(read)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *readTimeStore) CreateReadTimes(reads []*model.Read) ([]bool, error) {
+	created := make([]bool, len(reads))
+	// one insert per session, ids returned by a batch insert can't be matched up when some rows are skipped
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, read := range reads {
+			ok, err := createRead(tx, read)
+			if err != nil {
+				return err
+			}
+			created[i] = ok
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *readTimeStore) GetReadTimesByClientIDs(userID int, clientIDs []string) ([]model.Read, error) {
+	var reads []model.Read
+	if len(clientIDs) == 0 {
+		return reads, nil
+	}
+	if err := s.db.Unscoped().Where("user_id = ? AND client_id IN ?", userID, clientIDs).Find(&reads).Error; err != nil {
+		return nil, err
+	}
+	return reads, nil
+}
+
+func (s *readTimeStore) GetReadTimeChanges(userID int, after *ReadCursor, until time.Time, limit int) ([]model.Read, error) {
+	var reads []model.Read
+	query := s.db.Unscoped().Where("user_id = ? AND updated_at <= ?", userID, until)
+	if after != nil {
+		query = query.Where("(updated_at, id) > (?, ?)", after.UpdatedAt, after.ID)
 	}
-	return s.db.Create(readtime).Error
+	if err := query.Order("updated_at, id").Limit(limit).Find(&reads).Error; err != nil {
+		return nil, err
+	}
+	return reads, nil
+}
+
+func (s *readTimeStore) GetReadTimeByID(readID int, userID int) (*model.Read, error) {
+	var read model.Read
+	if err := s.db.Preload("BookLog").Where("id = ? AND user_id = ?", readID, userID).First(&read).Error; err != nil {
+		return nil, err
+	}
+	return &read, nil
+}
+
+func (s *readTimeStore) UpdateReadTime(read *model.Read) error {
+	result := s.db.Model(read).Where("user_id = ?", read.UserID).
+		Select("time", "book_log_id", "started_at", "ended_at", "pages").Updates(read)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *readTimeStore) DeleteReadTime(readID int, userID int) error {
+	// updated_at moves too, so the deletion shows up in the change feed
+	now := time.Now()
+	result := s.db.Model(&model.Read{}).Where("id = ? AND user_id = ?", readID, userID).
+		Updates(map[string]interface{}{"deleted_at": now, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *readTimeStore) GetReadTimes(userID int, filter ReadFilter, page, pageSize int) ([]model.Read, int64, error) {
+	var reads []model.Read
+	var total int64
+	query := s.db.Model(&model.Read{}).Where("user_id = ?", userID).Scopes(inRange("started_at", filter.From, filter.To))
+	if filter.BookID != nil {
+		query = query.Where("book_log_id = ?", *filter.BookID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Preload("BookLog").Order("started_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&reads).Error; err != nil {
+		return nil, 0, err
+	}
+	return reads, total, nil
 }
 
 func (s *readTimeStore) GetWeeklyReadTime(userID int) ([]model.Read, error) {
 	var reads []model.Read
 	timeRange := time.Now().AddDate(0, 0, -7) // get the last 7 days
-	if err := s.db.Where("user_id = ? AND created_at >= ?", userID, timeRange).Order("created_at ASC").Find(&reads).Error; err != nil {
+	if err := s.db.Where("user_id = ? AND started_at >= ?", userID, timeRange).Order("started_at ASC").Find(&reads).Error; err != nil {
 		return nil, err
 	}
 	return reads, nil
-}
\ No newline at end of file
+}
+
+func (s *readTimeStore) GetTotalReadTime(userID int) (int, error) {
+	var total int
+	if err := s.db.Model(&model.Read{}).Where("user_id = ?", userID).Select("COALESCE(SUM(time), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (s *readTimeStore) GetBookReadTotals(userID int) (map[uint]int, error) {
+	var rows []struct {
+		BookLogID uint
+		Minutes   int
+	}
+	if err := s.db.Model(&model.Read{}).Where("user_id = ? AND book_log_id IS NOT NULL", userID).
+		Select("book_log_id, SUM(time) AS minutes").Group("book_log_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		totals[row.BookLogID] = row.Minutes
+	}
+	return totals, nil
+}
+
+func (s *readTimeStore) GetReadTimeBuckets(userID int, unit string, tz *time.Location, from, to time.Time) ([]ReadBucket, error) {
+	var buckets []ReadBucket
+	// buckets are local timestamps, the sessions are matched by their local start
+	err := s.db.Raw(`SELECT to_char(b.start, 'YYYY-MM-DD') AS start, COALESCE(SUM(r.time), 0) AS minutes, COUNT(r.id) AS sessions
+		FROM generate_series(date_trunc(@unit, CAST(@from AS timestamp)), CAST(@to AS timestamp) - interval '1 microsecond', ('1 ' || @unit)::interval) AS b(start)
+		LEFT JOIN reads r ON r.user_id = @user AND r.deleted_at IS NULL
+			AND r.started_at >= @fromAt AND r.started_at < @toAt
+			AND date_trunc(@unit, r.started_at AT TIME ZONE @tz) = b.start
+		GROUP BY b.start ORDER BY b.start`,
+		map[string]interface{}{
+			"unit":   unit,
+			"from":   from.In(tz).Format("2006-01-02 15:04:05"),
+			"to":     to.In(tz).Format("2006-01-02 15:04:05"),
+			"user":   userID,
+			"fromAt": from,
+			"toAt":   to,
+			"tz":     tz.String(),
+		}).Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func (s *readTimeStore) GetReadStreaks(userID int, tz *time.Location, minMinutes int) ([]ReadStreak, error) {
+	var streaks []ReadStreak
+	// consecutive days keep the same distance to their row number, so that distance identifies the streak
+	err := s.db.Raw(`SELECT to_char(MIN(d.day), 'YYYY-MM-DD') AS start, to_char(MAX(d.day), 'YYYY-MM-DD') AS "end", COUNT(*) AS days
+		FROM (
+			SELECT day, day - CAST(ROW_NUMBER() OVER (ORDER BY day) AS int) AS streak
+			FROM (
+				SELECT CAST(started_at AT TIME ZONE @tz AS date) AS day
+				FROM reads WHERE user_id = @user AND deleted_at IS NULL
+				GROUP BY 1 HAVING SUM(time) >= @min
+			) days
+		) d
+		GROUP BY d.streak ORDER BY MIN(d.day)`,
+		map[string]interface{}{"tz": tz.String(), "user": userID, "min": minMinutes}).Scan(&streaks).Error
+	if err != nil {
+		return nil, err
+	}
+	return streaks, nil
+}
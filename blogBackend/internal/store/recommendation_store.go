@@ -0,0 +1,58 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RecommendationStore interface {
+	Migrate() error
+
+	//this function used to swap a user's recommendations for a freshly computed set.
+	ReplaceForUser(userID int, recs []model.Recommendation) error
+	GetByUserID(userID int, limit int) ([]model.Recommendation, error)
+
+	//this function used to list the users that have recommendations stored.
+	GetUserIDs() ([]uint, error)
+}
+
+type recommendationStore struct {
+	db *gorm.DB
+}
+
+func NewRecommendationStore(db *gorm.DB) RecommendationStore {
+	return &recommendationStore{db: db}
+}
+
+func (s *recommendationStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Recommendation{})
+}
+
+func (s *recommendationStore) ReplaceForUser(userID int, recs []model.Recommendation) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Recommendation{}).Error; err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		return tx.Create(&recs).Error
+	})
+}
+
+func (s *recommendationStore) GetByUserID(userID int, limit int) ([]model.Recommendation, error) {
+	var recs []model.Recommendation
+	if err := s.db.Where("user_id = ?", userID).Order("score DESC").Limit(limit).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+func (s *recommendationStore) GetUserIDs() ([]uint, error) {
+	var userIDs []uint
+	if err := s.db.Model(&model.Recommendation{}).Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Review feed orderings
+const (
+	ReviewSortRecent  = "recent"
+	ReviewSortPopular = "popular"
+)
+
+type ReviewStore interface {
+	Migrate() error
+	CreateReview(review *model.Review) error
+	GetReviewByBookLogID(bookLogID int) (*model.Review, error)
+
+	//this function used to unpublish a review, its likes and comments are removed with it.
+	DeleteReviewByBookLogID(bookLogID int, userID int) error
+
+	//these functions only return reviews whose book log the viewer may see, viewerID 0 is an anonymous visitor.
+	GetReviewByID(reviewID int, viewerID int) (*model.Review, error)
+	GetReviews(viewerID int, sort string, page, pageSize int) ([]model.Review, int64, error)
+
+	//these functions return whether anything changed, the counters only move when it did.
+	LikeReview(reviewID int, userID int) (bool, error)
+	UnlikeReview(reviewID int, userID int) (bool, error)
+
+	//this function used to add a comment to a review, the comment is filled in with its author.
+	CreateComment(comment *model.ReviewComment) error
+	DeleteComment(commentID int, reviewID int, userID int) error
+	GetComments(reviewID int, page, pageSize int) ([]model.ReviewComment, int64, error)
+}
+
+type reviewStore struct {
+	db *gorm.DB
+}
+
+func NewReviewStore(db *gorm.DB) ReviewStore {
+	return &reviewStore{db: db}
+}
+
+func (s *reviewStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Review{}, &model.ReviewLike{}, &model.ReviewComment{})
+}
+
+func (s *reviewStore) CreateReview(review *model.Review) error {
+	return s.db.Create(review).Error
+}
+
+func (s *reviewStore) GetReviewByBookLogID(bookLogID int) (*model.Review, error) {
+	var review model.Review
+	if err := s.db.Where("book_log_id = ?", bookLogID).First(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (s *reviewStore) DeleteReviewByBookLogID(bookLogID int, userID int) error {
+	result := s.db.Where("book_log_id = ? AND user_id = ?", bookLogID, userID).Delete(&model.Review{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// joinLiveBooks hides reviews whose book log has been deleted
+func joinLiveBooks(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN book_logs ON book_logs.id = reviews.book_log_id AND book_logs.deleted_at IS NULL")
+}
+
+func (s *reviewStore) GetReviewByID(reviewID int, viewerID int) (*model.Review, error) {
+	var review model.Review
+	if err := s.db.Scopes(joinLiveBooks, VisibleBookLogs(viewerID)).Preload("BookLog").Preload("User").Where("reviews.id = ?", reviewID).First(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (s *reviewStore) GetReviews(viewerID int, sort string, page, pageSize int) ([]model.Review, int64, error) {
+	var reviews []model.Review
+	var total int64
+
+	if err := s.db.Model(&model.Review{}).Scopes(joinLiveBooks, VisibleBookLogs(viewerID)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Scopes(joinLiveBooks, VisibleBookLogs(viewerID)).Preload("BookLog").Preload("User")
+	if sort == ReviewSortPopular {
+		query = query.Order("reviews.like_count DESC").Order("reviews.comment_count DESC")
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("reviews.published_at DESC").Offset(offset).Limit(pageSize).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+func (s *reviewStore) LikeReview(reviewID int, userID int) (bool, error) {
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		like := &model.ReviewLike{ReviewID: uint(reviewID), UserID: uint(userID)}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(like)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		changed = true
+		return tx.Model(&model.Review{}).Where("id = ?", reviewID).Update("like_count", gorm.Expr("like_count + ?", 1)).Error
+	})
+	return changed, err
+}
+
+func (s *reviewStore) UnlikeReview(reviewID int, userID int) (bool, error) {
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("review_id = ? AND user_id = ?", reviewID, userID).Delete(&model.ReviewLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		changed = true
+		return tx.Model(&model.Review{}).Where("id = ?", reviewID).Update("like_count", gorm.Expr("GREATEST(like_count - ?, 0)", 1)).Error
+	})
+	return changed, err
+}
+
+func (s *reviewStore) CreateComment(comment *model.ReviewComment) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Review{}).Where("id = ?", comment.ReviewID).Update("comment_count", gorm.Expr("comment_count + ?", 1)).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.db.First(&comment.User, comment.UserID).Error
+}
+
+func (s *reviewStore) DeleteComment(commentID int, reviewID int, userID int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND review_id = ? AND user_id = ?", commentID, reviewID, userID).Delete(&model.ReviewComment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&model.Review{}).Where("id = ?", reviewID).Update("comment_count", gorm.Expr("GREATEST(comment_count - ?, 0)", 1)).Error
+	})
+}
+
+func (s *reviewStore) GetComments(reviewID int, page, pageSize int) ([]model.ReviewComment, int64, error) {
+	var comments []model.ReviewComment
+	var total int64
+	if err := s.db.Model(&model.ReviewComment{}).Where("review_id = ?", reviewID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := s.db.Preload("User").Where("review_id = ?", reviewID).Order("created_at ASC").Offset(offset).Limit(pageSize).Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
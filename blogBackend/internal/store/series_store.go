@@ -0,0 +1,156 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// SeriesSummary is a series with the number of book logs in it
+type SeriesSummary struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	BookCount   int64  `json:"book_count"`
+}
+
+type SeriesStore interface {
+	Migrate() error
+	CreateSeries(series *model.Series) error
+
+	//this function used to get a series with its books in reading order.
+	GetSeriesByID(seriesID int, userID int) (*model.Series, error)
+	GetSeriesList(userID int) ([]SeriesSummary, error)
+	GetSeriesByIDs(userID int, ids []uint) ([]model.Series, error)
+	UpdateSeries(series *model.Series) error
+
+	//this function used to delete a series, its books stay in the library without a series.
+	DeleteSeries(seriesID int, userID int) error
+
+	//this function used to get the highest position used in a series, 0 when it is empty.
+	GetMaxPosition(seriesID uint) (float64, error)
+
+	//this function used to set the position of every given book in the series, in one transaction.
+	ReorderBooks(seriesID uint, userID int, positions map[uint]float64) error
+
+	//this function used to get the first book after position in a series, skipping editions of the given work.
+	GetNextInSeries(userID int, seriesID uint, position float64, workID *uint) (*model.BookLog, error)
+}
+
+type seriesStore struct {
+	db *gorm.DB
+}
+
+func NewSeriesStore(db *gorm.DB) SeriesStore {
+	return &seriesStore{db: db}
+}
+
+func (s *seriesStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Series{})
+}
+
+func (s *seriesStore) CreateSeries(series *model.Series) error {
+	return s.db.Create(series).Error
+}
+
+func (s *seriesStore) GetSeriesByID(seriesID int, userID int) (*model.Series, error) {
+	var series model.Series
+	err := s.db.Preload("Books", func(db *gorm.DB) *gorm.DB {
+		return db.Order("series_position ASC NULLS LAST, id ASC")
+	}).Where("id = ? AND user_id = ?", seriesID, userID).First(&series).Error
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (s *seriesStore) GetSeriesList(userID int) ([]SeriesSummary, error) {
+	var list []SeriesSummary
+	err := s.db.Model(&model.Series{}).
+		Select("series.id, series.name, series.description, COUNT(book_logs.id) AS book_count").
+		Joins("LEFT JOIN book_logs ON book_logs.series_id = series.id AND book_logs.deleted_at IS NULL").
+		Where("series.user_id = ?", userID).
+		Group("series.id").
+		Order("series.name ASC").
+		Scan(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *seriesStore) GetSeriesByIDs(userID int, ids []uint) ([]model.Series, error) {
+	var list []model.Series
+	if len(ids) == 0 {
+		return list, nil
+	}
+	if err := s.db.Where("user_id = ? AND id IN ?", userID, ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *seriesStore) UpdateSeries(series *model.Series) error {
+	result := s.db.Model(&model.Series{}).Where("id = ? AND user_id = ?", series.ID, series.UserID).
+		Select("name", "description").Updates(series)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *seriesStore) DeleteSeries(seriesID int, userID int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.BookLog{}).Where("series_id = ? AND user_id = ?", seriesID, userID).
+			Updates(map[string]interface{}{"series_id": nil, "series_position": nil}).Error; err != nil {
+			return err
+		}
+		// hard delete so the (user, name) unique index is free again
+		result := tx.Unscoped().Where("id = ? AND user_id = ?", seriesID, userID).Delete(&model.Series{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (s *seriesStore) GetMaxPosition(seriesID uint) (float64, error) {
+	var max float64
+	err := s.db.Model(&model.BookLog{}).Where("series_id = ?", seriesID).
+		Select("COALESCE(MAX(series_position), 0)").Scan(&max).Error
+	return max, err
+}
+
+func (s *seriesStore) ReorderBooks(seriesID uint, userID int, positions map[uint]float64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for bookID, position := range positions {
+			result := tx.Model(&model.BookLog{}).Where("id = ? AND user_id = ? AND series_id = ?", bookID, userID, seriesID).
+				Update("series_position", position)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+func (s *seriesStore) GetNextInSeries(userID int, seriesID uint, position float64, workID *uint) (*model.BookLog, error) {
+	query := s.db.Where("user_id = ? AND series_id = ? AND series_position > ?", userID, seriesID, position)
+	if workID != nil {
+		query = query.Where("work_id IS NULL OR work_id <> ?", *workID)
+	}
+	var book model.BookLog
+	if err := query.Order("series_position ASC, id ASC").First(&book).Error; err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
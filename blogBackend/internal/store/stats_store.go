@@ -0,0 +1,233 @@
+package store
+
+import (
+	"project/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// topAuthorLimit caps the per-author breakdown
+const topAuthorLimit = 20
+
+// KeyCount is a group of a breakdown with its number of books
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// RatingCount is how many books got a rating
+type RatingCount struct {
+	Rating int   `json:"rating"`
+	Count  int64 `json:"count"`
+}
+
+// MonthCount is the books finished in a month, formatted YYYY-MM
+type MonthCount struct {
+	Month string `json:"month"`
+	Books int64  `json:"books"`
+	Pages int64  `json:"pages"`
+}
+
+// BookLength identifies a book by its page count
+type BookLength struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	PageCount int    `json:"page_count"`
+}
+
+// LibraryStats is computed in SQL. Breakdowns and ratings cover books added in the range,
+// reading figures cover books finished in the range.
+type LibraryStats struct {
+	TotalBooks         int64         `json:"total_books"`
+	ByStatus           []KeyCount    `json:"by_status"`
+	ByCategory         []KeyCount    `json:"by_category"`
+	ByAuthor           []KeyCount    `json:"by_author"`
+	AverageRating      *float64      `json:"average_rating"`
+	RatedBooks         int64         `json:"rated_books"`
+	RatingDistribution []RatingCount `json:"rating_distribution"`
+	BooksFinished      int64         `json:"books_finished"`
+	PagesRead          int64         `json:"pages_read"`
+	FinishedPerMonth   []MonthCount  `json:"finished_per_month"`
+	Longest            *BookLength   `json:"longest"`
+	Shortest           *BookLength   `json:"shortest"`
+}
+
+// LeaderboardRow is what a reader did in a leaderboard period
+type LeaderboardRow struct {
+	UserID        uint
+	UserName      string
+	Minutes       int64
+	BooksFinished int64
+}
+
+type StatsStore interface {
+	//this function used to compute the library statistics of a user, from and to may be nil for an open range.
+	GetLibraryStats(userID int, from, to *time.Time) (*LibraryStats, error)
+
+	//this function used to get when the book logs of a user last changed, deleting one counts as a change.
+	GetLibraryVersion(userID int) (time.Time, error)
+
+	//this function used to total the minutes read and books finished in [from, to) by the user and the readers they
+	//follow who follow them back and didn't opt out of leaderboards. Books only count when the user may see them.
+	GetLeaderboard(viewerID int, from, to time.Time) ([]LeaderboardRow, error)
+}
+
+type statsStore struct {
+	db *gorm.DB
+}
+
+func NewStatsStore(db *gorm.DB) StatsStore {
+	return &statsStore{db: db}
+}
+
+// inRange limits a timestamp column to [from, to)
+func inRange(column string, from, to *time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if from != nil {
+			db = db.Where(column+" >= ?", *from)
+		}
+		if to != nil {
+			db = db.Where(column+" < ?", *to)
+		}
+		return db
+	}
+}
+
+func (s *statsStore) GetLibraryStats(userID int, from, to *time.Time) (*LibraryStats, error) {
+	stats := &LibraryStats{}
+	added := func() *gorm.DB {
+		return s.db.Model(&model.BookLog{}).Where("user_id = ?", userID).Scopes(inRange("created_at", from, to))
+	}
+	// finished books are counted like goals and leaderboards count them
+	finished := func() *gorm.DB {
+		return s.db.Model(&model.BookLog{}).Where("user_id = ? AND status = ? AND finished_at IS NOT NULL", userID, model.BookStatusRead).
+			Scopes(inRange("finished_at", from, to))
+	}
+
+	if err := added().Count(&stats.TotalBooks).Error; err != nil {
+		return nil, err
+	}
+	if err := added().Select("COALESCE(NULLIF(status, ''), 'Unknown') AS key, COUNT(*) AS count").
+		Group("key").Order("count DESC, key ASC").Scan(&stats.ByStatus).Error; err != nil {
+		return nil, err
+	}
+	if err := added().Select("COALESCE(NULLIF(category, ''), 'Uncategorized') AS key, COUNT(*) AS count").
+		Group("key").Order("count DESC, key ASC").Scan(&stats.ByCategory).Error; err != nil {
+		return nil, err
+	}
+	if err := added().Select("COALESCE(NULLIF(author, ''), 'Unknown') AS key, COUNT(*) AS count").
+		Group("key").Order("count DESC, key ASC").Limit(topAuthorLimit).Scan(&stats.ByAuthor).Error; err != nil {
+		return nil, err
+	}
+
+	// a rating of 0 means "not rated"
+	var rating struct {
+		Average *float64
+		Rated   int64
+	}
+	if err := added().Where("my_rating > 0").Select("AVG(my_rating) AS average, COUNT(*) AS rated").
+		Scan(&rating).Error; err != nil {
+		return nil, err
+	}
+	stats.AverageRating, stats.RatedBooks = rating.Average, rating.Rated
+	if err := added().Where("my_rating > 0").Select("my_rating AS rating, COUNT(*) AS count").
+		Group("my_rating").Order("my_rating ASC").Scan(&stats.RatingDistribution).Error; err != nil {
+		return nil, err
+	}
+
+	var totals struct {
+		Books int64
+		Pages int64
+	}
+	if err := finished().Select("COUNT(*) AS books, COALESCE(SUM(page_count), 0) AS pages").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	stats.BooksFinished, stats.PagesRead = totals.Books, totals.Pages
+	if err := finished().
+		Select("to_char(date_trunc('month', finished_at), 'YYYY-MM') AS month, COUNT(*) AS books, COALESCE(SUM(page_count), 0) AS pages").
+		Group("month").Order("month ASC").Scan(&stats.FinishedPerMonth).Error; err != nil {
+		return nil, err
+	}
+
+	var err error
+	if stats.Longest, err = s.bookByLength(added(), "page_count DESC"); err != nil {
+		return nil, err
+	}
+	if stats.Shortest, err = s.bookByLength(added(), "page_count ASC"); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (s *statsStore) GetLibraryVersion(userID int) (time.Time, error) {
+	var version *time.Time
+	err := s.db.Model(&model.BookLog{}).Unscoped().Where("user_id = ?", userID).
+		Select("MAX(GREATEST(updated_at, deleted_at))").Scan(&version).Error
+	if err != nil || version == nil {
+		return time.Time{}, err
+	}
+	return *version, nil
+}
+
+// bookByLength returns the first book with a known page count in the given order, nil when there is none
+func (s *statsStore) bookByLength(query *gorm.DB, order string) (*BookLength, error) {
+	var books []BookLength
+	if err := query.Where("page_count > 0").Select("id, title, author, page_count").
+		Order(order).Order("id ASC").Limit(1).Scan(&books).Error; err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, nil
+	}
+	return &books[0], nil
+}
+
+func (s *statsStore) GetLeaderboard(viewerID int, from, to time.Time) ([]LeaderboardRow, error) {
+	var users []model.UserLog
+	if err := s.db.Select("id", "user_name").
+		Where(`id = ? OR (leaderboard_opt_out = ? AND id IN (SELECT follows.followee_id FROM follows
+			JOIN follows AS back ON back.follower_id = follows.followee_id AND back.followee_id = follows.follower_id
+			WHERE follows.follower_id = ?))`, viewerID, false, viewerID).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	var minutes []struct {
+		UserID  uint
+		Minutes int64
+	}
+	if err := s.db.Model(&model.Read{}).Select("user_id, SUM(time) AS minutes").
+		Where("user_id IN ? AND started_at >= ? AND started_at < ?", ids, from, to).
+		Group("user_id").Scan(&minutes).Error; err != nil {
+		return nil, err
+	}
+	var books []struct {
+		UserID uint
+		Books  int64
+	}
+	if err := s.db.Model(&model.BookLog{}).Scopes(VisibleBookLogs(viewerID)).Select("book_logs.user_id, COUNT(*) AS books").
+		Where("book_logs.user_id IN ? AND book_logs.status = ? AND book_logs.finished_at >= ? AND book_logs.finished_at < ?", ids, model.BookStatusRead, from, to).
+		Group("book_logs.user_id").Scan(&books).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]LeaderboardRow, len(users))
+	index := make(map[uint]int, len(users))
+	for i, user := range users {
+		rows[i] = LeaderboardRow{UserID: user.ID, UserName: user.UserName}
+		index[user.ID] = i
+	}
+	for _, total := range minutes {
+		rows[index[total.UserID]].Minutes = total.Minutes
+	}
+	for _, total := range books {
+		rows[index[total.UserID]].BooksFinished = total.Books
+	}
+	return rows, nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"project/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TimerStore interface {
+	Migrate() error
+
+	//this function used to start the timer of a user, gorm.ErrDuplicatedKey when the user already has one.
+	CreateTimer(timer *model.ReadTimer) error
+
+	//this function used to get the timer of the user with its book.
+	GetTimer(userID int) (*model.ReadTimer, error)
+	UpdateTimer(timer *model.ReadTimer) error
+
+	//this function used to remove a timer, only one caller gets to remove it so only one records the session.
+	DeleteTimer(timerID uint) error
+
+	//this function used to find the timers started before a time, candidates for closing.
+	GetTimersStartedBefore(before time.Time) ([]model.ReadTimer, error)
+}
+
+type timerStore struct {
+	db *gorm.DB
+}
+
+func NewTimerStore(db *gorm.DB) TimerStore {
+	return &timerStore{db: db}
+}
+
+func (s *timerStore) Migrate() error {
+	return s.db.AutoMigrate(&model.ReadTimer{})
+}
+
+func (s *timerStore) CreateTimer(timer *model.ReadTimer) error {
+	// two devices may start at the same moment, the unique user id lets only one of them win
+	result := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(timer)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+func (s *timerStore) GetTimer(userID int) (*model.ReadTimer, error) {
+	var timer model.ReadTimer
+	if err := s.db.Preload("BookLog").Where("user_id = ?", userID).First(&timer).Error; err != nil {
+		return nil, err
+	}
+	return &timer, nil
+}
+
+func (s *timerStore) UpdateTimer(timer *model.ReadTimer) error {
+	result := s.db.Model(timer).Select("resumed_at", "paused_at", "accumulated_seconds").Updates(timer)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *timerStore) DeleteTimer(timerID uint) error {
+	result := s.db.Delete(&model.ReadTimer{}, timerID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *timerStore) GetTimersStartedBefore(before time.Time) ([]model.ReadTimer, error) {
+	var timers []model.ReadTimer
+	if err := s.db.Where("started_at <= ?", before).Find(&timers).Error; err != nil {
+		return nil, err
+	}
+	return timers, nil
+}
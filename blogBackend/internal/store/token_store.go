@@ -0,0 +1,66 @@
+package store
+
+import (
+	"project/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenStore interface {
+	Migrate() error
+	CreateToken(token *model.PersonalToken) error
+	GetTokens(userID int) ([]model.PersonalToken, error)
+	DeleteToken(tokenID int, userID int) error
+
+	//this function used to look a token up by the hash of its value.
+	FindTokenByHash(hash string) (*model.PersonalToken, error)
+	TouchToken(tokenID uint, at time.Time) error
+}
+
+type tokenStore struct {
+	db *gorm.DB
+}
+
+func NewTokenStore(db *gorm.DB) TokenStore {
+	return &tokenStore{db: db}
+}
+
+func (s *tokenStore) Migrate() error {
+	return s.db.AutoMigrate(&model.PersonalToken{})
+}
+
+func (s *tokenStore) CreateToken(token *model.PersonalToken) error {
+	return s.db.Create(token).Error
+}
+
+func (s *tokenStore) GetTokens(userID int) ([]model.PersonalToken, error) {
+	var tokens []model.PersonalToken
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *tokenStore) DeleteToken(tokenID int, userID int) error {
+	result := s.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&model.PersonalToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *tokenStore) FindTokenByHash(hash string) (*model.PersonalToken, error) {
+	var token model.PersonalToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *tokenStore) TouchToken(tokenID uint, at time.Time) error {
+	return s.db.Model(&model.PersonalToken{}).Where("id = ?", tokenID).Update("last_used_at", at).Error
+}
@@ -2,14 +2,18 @@ package store
 
 import (
 	"project/internal/model"
-	"gorm.io/gorm"
 
+	"gorm.io/gorm"
 )
 
 type UserStore interface {
 	CreateUser(user *model.UserLog) error
 	Migrate() error
 	FindUserByEmail(email string) (*model.UserLog, error)
+	FindUserByID(userID int) (*model.UserLog, error)
+
+	//this function used to save the settings of the user: default visibility, timezone, streak threshold and leaderboard opt-out.
+	UpdateSettings(user *model.UserLog) error
 }
 
 type userStore struct {
@@ -34,3 +38,22 @@ func (s *userStore) FindUserByEmail(email string) (*model.UserLog, error) {
 	}
 	return &user, nil
 }
+
+func (s *userStore) FindUserByID(userID int) (*model.UserLog, error) {
+	var user model.UserLog
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *userStore) UpdateSettings(user *model.UserLog) error {
+	result := s.db.Model(user).Select("default_visibility", "timezone", "streak_min_minutes", "leaderboard_opt_out").Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
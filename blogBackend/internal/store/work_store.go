@@ -0,0 +1,89 @@
+package store
+
+import (
+	"project/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type WorkStore interface {
+	Migrate() error
+
+	//this function used to create a work and attach the given book logs to it as editions, in one transaction.
+	CreateWork(work *model.Work, bookIDs []uint) error
+	GetWorkByID(workID int, userID int) (*model.Work, error)
+	GetWorks(userID int) ([]model.Work, error)
+
+	//this function used to delete a work, its editions stay in the library as separate books.
+	DeleteWork(workID int, userID int) error
+}
+
+type workStore struct {
+	db *gorm.DB
+}
+
+func NewWorkStore(db *gorm.DB) WorkStore {
+	return &workStore{db: db}
+}
+
+func (s *workStore) Migrate() error {
+	return s.db.AutoMigrate(&model.Work{})
+}
+
+func (s *workStore) CreateWork(work *model.Work, bookIDs []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(work).Error; err != nil {
+			return err
+		}
+		if len(bookIDs) == 0 {
+			return nil
+		}
+		result := tx.Model(&model.BookLog{}).Where("id IN ? AND user_id = ?", bookIDs, work.UserID).Update("work_id", work.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(bookIDs)) {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (s *workStore) GetWorkByID(workID int, userID int) (*model.Work, error) {
+	var work model.Work
+	err := s.db.Preload("Editions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("id = ? AND user_id = ?", workID, userID).First(&work).Error
+	if err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+func (s *workStore) GetWorks(userID int) ([]model.Work, error) {
+	var works []model.Work
+	err := s.db.Preload("Editions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("user_id = ?", userID).Order("title ASC").Find(&works).Error
+	if err != nil {
+		return nil, err
+	}
+	return works, nil
+}
+
+func (s *workStore) DeleteWork(workID int, userID int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.BookLog{}).Where("work_id = ? AND user_id = ?", workID, userID).
+			Update("work_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND user_id = ?", workID, userID).Delete(&model.Work{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
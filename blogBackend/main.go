@@ -7,6 +7,7 @@ import (
 	"project/internal/config"
 	"project/internal/service"
 	"project/internal/store"
+	"time"
 )
 
 func main() {
@@ -24,11 +25,77 @@ func main() {
 	readtimeStore := store.NewReadTimeStore(db)
 	chatStore := store.NewChatLogStore(db)
 	messageStore := store.NewMessageStore(db)
+	importStore := store.NewImportJobStore(db)
+	highlightStore := store.NewHighlightStore(db)
+	reviewStore := store.NewReviewStore(db)
+	goalStore := store.NewGoalStore(db)
+	recommendationStore := store.NewRecommendationStore(db)
+	seriesStore := store.NewSeriesStore(db)
+	workStore := store.NewWorkStore(db)
+	followStore := store.NewFollowStore(db)
+	statsStore := store.NewStatsStore(db)
+	loanStore := store.NewLoanStore(db)
+	notificationStore := store.NewNotificationStore(db)
+	queueStore := store.NewQueueStore(db)
+	tokenStore := store.NewTokenStore(db)
+	timerStore := store.NewTimerStore(db)
+	minutesGoalStore := store.NewMinutesGoalStore(db)
+	catalogStore := store.NewCatalogStore(db)
+	var blobStore store.BlobStore
+	var err error
+	switch cfg.BLOB_BACKEND {
+	case "s3":
+		blobStore, err = store.NewS3BlobStore(store.S3Config{
+			Endpoint:  cfg.S3_ENDPOINT,
+			Region:    cfg.S3_REGION,
+			Bucket:    cfg.S3_BUCKET,
+			AccessKey: cfg.S3_ACCESS_KEY,
+			SecretKey: cfg.S3_SECRET_KEY,
+		})
+	default:
+		blobStore, err = store.NewLocalBlobStore(cfg.BLOB_LOCAL_DIR)
+	}
+	if err != nil {
+		log.Fatalf("Error creating blob store: %v", err)
+	}
+	statsCacheTTL, err := time.ParseDuration(cfg.STATS_CACHE_TTL)
+	if err != nil {
+		log.Fatalf("Invalid STATS_CACHE_TTL: %v", err)
+	}
+	leaderboardCacheTTL, err := time.ParseDuration(cfg.LEADERBOARD_CACHE_TTL)
+	if err != nil {
+		log.Fatalf("Invalid LEADERBOARD_CACHE_TTL: %v", err)
+	}
+	timerMaxDuration, err := time.ParseDuration(cfg.READ_TIMER_MAX_DURATION)
+	if err != nil {
+		log.Fatalf("Invalid READ_TIMER_MAX_DURATION: %v", err)
+	}
 	authService := service.NewAuthService(userStore)
-	logService := service.NewLogService(bookLogStore)
+	logService := service.NewLogService(bookLogStore, seriesStore)
 	forumService := service.NewForumService(forumStore)
 	chatService := service.NewChatService(chatStore, messageStore, cfg.OPENAI_API_KEY)
-	readTimeService := service.NewReadService(readtimeStore)
+	readTimeService := service.NewReadService(readtimeStore, bookLogStore, userStore)
+	importService := service.NewImportService(importStore, bookLogStore)
+	exportService := service.NewExportService(bookLogStore, readtimeStore)
+	highlightService := service.NewHighlightService(highlightStore, bookLogStore)
+	reviewService := service.NewReviewService(reviewStore, bookLogStore)
+	goalService := service.NewGoalService(goalStore, bookLogStore)
+	recommendationService := service.NewRecommendationService(recommendationStore, bookLogStore)
+	coverService := service.NewCoverService(blobStore, bookLogStore)
+	duplicateService := service.NewDuplicateService(bookLogStore)
+	seriesService := service.NewSeriesService(seriesStore, bookLogStore)
+	workService := service.NewWorkService(workStore, bookLogStore)
+	userService := service.NewUserService(userStore, followStore, bookLogStore)
+	statsService := service.NewStatsService(statsStore, statsCacheTTL)
+	notificationService := service.NewNotificationService(notificationStore)
+	loanService := service.NewLoanService(loanStore, bookLogStore, userStore, notificationService)
+	queueService := service.NewQueueService(queueStore, bookLogStore)
+	epubService := service.NewEPUBService(bookLogStore, coverService)
+	tokenService := service.NewTokenService(tokenStore, userStore)
+	opdsService := service.NewOPDSService(catalogStore, bookLogStore, userStore)
+	timerService := service.NewTimerService(timerStore, bookLogStore, readTimeService, timerMaxDuration)
+	minutesGoalService := service.NewMinutesGoalService(minutesGoalStore, readtimeStore, userStore)
+	leaderboardService := service.NewLeaderboardService(statsStore, userStore, leaderboardCacheTTL)
 	// database migrations
 	fmt.Println("Running database migrations...")
 	if err := userStore.Migrate(); err != nil {
@@ -57,14 +124,94 @@ func main() {
 	if err := messageStore.Migrate(); err != nil {
 		log.Fatalf("Error migrating message table: %v", err)
 	}
+	if err := importStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating import job table: %v", err)
+	}
+	if err := highlightStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating highlight table: %v", err)
+	}
+	if err := reviewStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating review table: %v", err)
+	}
+	if err := goalStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating reading goal table: %v", err)
+	}
+	if err := recommendationStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating recommendation table: %v", err)
+	}
+	if err := seriesStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating series table: %v", err)
+	}
+	if err := workStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating work table: %v", err)
+	}
+	if err := followStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating follow table: %v", err)
+	}
+	if err := loanStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating loan table: %v", err)
+	}
+	if err := notificationStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating notification table: %v", err)
+	}
+	if err := queueStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating queue table: %v", err)
+	}
+	if err := tokenStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating personal token table: %v", err)
+	}
+	if err := timerStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating read timer table: %v", err)
+	}
+	if err := minutesGoalStore.Migrate(); err != nil {
+		log.Fatalf("Error migrating minutes goal table: %v", err)
+	}
 	fmt.Println("Forum table migration successful")
+
+	// background jobs
+	refreshInterval, err := time.ParseDuration(cfg.RECOMMENDATION_REFRESH_INTERVAL)
+	if err != nil {
+		log.Fatalf("Invalid RECOMMENDATION_REFRESH_INTERVAL: %v", err)
+	}
+	go recommendationService.StartRefresher(refreshInterval)
+	overdueInterval, err := time.ParseDuration(cfg.LOAN_OVERDUE_CHECK_INTERVAL)
+	if err != nil {
+		log.Fatalf("Invalid LOAN_OVERDUE_CHECK_INTERVAL: %v", err)
+	}
+	go loanService.StartOverdueNotifier(overdueInterval)
+	timerCheckInterval, err := time.ParseDuration(cfg.READ_TIMER_CHECK_INTERVAL)
+	if err != nil {
+		log.Fatalf("Invalid READ_TIMER_CHECK_INTERVAL: %v", err)
+	}
+	go timerService.StartStaleTimerCloser(timerCheckInterval)
 	// create API dependencies
 	deps := api.HandlerDependencies{
-		AuthService:  authService,
-		LogService:   logService,
-		ForumService: forumService,
-		ReadService:  readTimeService,
-		ChatService:  chatService,
+		AuthService:           authService,
+		LogService:            logService,
+		ForumService:          forumService,
+		ReadService:           readTimeService,
+		ChatService:           chatService,
+		ImportService:         importService,
+		ExportService:         exportService,
+		HighlightService:      highlightService,
+		ReviewService:         reviewService,
+		GoalService:           goalService,
+		RecommendationService: recommendationService,
+		CoverService:          coverService,
+		DuplicateService:      duplicateService,
+		SeriesService:         seriesService,
+		WorkService:           workService,
+		UserService:           userService,
+		StatsService:          statsService,
+		LoanService:           loanService,
+		NotificationService:   notificationService,
+		QueueService:          queueService,
+		EPUBService:           epubService,
+		TokenService:          tokenService,
+		OPDSService:           opdsService,
+		TimerService:          timerService,
+		MinutesGoalService:    minutesGoalService,
+		LeaderboardService:    leaderboardService,
 	}
 
 	// create router